@@ -2,10 +2,12 @@ package client
 
 import (
 	conf "aio/client/src/settings"
+	"aio/common/src/codec"
 	logg "aio/common/src/logger"
 	mod "aio/common/src/model"
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
@@ -40,6 +42,11 @@ type Client struct {
 	Logger             *logg.Logger
 	Connection         *net.Conn
 	IsConnectionActive IsActive
+	Router             *Router
+	Codec              *codec.Codec
+	Printer            func(*mod.ResponseModel)
+	leaving            Alive
+	outbox             outbox
 }
 
 func (c *Client) Name() string {
@@ -53,6 +60,7 @@ func (c *Client) Init(configFilePath string) (err error) {
 
 	c.IsConnectionActive = new(Alive)
 	c.IsConnectionActive.Update(false)
+	c.Router = NewRouter()
 
 	c.Logger = new(logg.Logger)
 	c.Logger.Entity = c
@@ -68,17 +76,53 @@ func (c *Client) SendLoopSync(callback func(c *Client) (int, error)) (err error)
 }
 
 func (c *Client) RecvLoopAsync() {
+	c.RecvLoopAsyncHandle(func(c *Client, err error) {
+		c.Logger.Fatal(err)
+	})
+}
+
+// Receive responses in the background, calling the error handler once
+// the connection can no longer be used
+func (c *Client) RecvLoopAsyncHandle(errorHandler func(*Client, error)) {
 	go func() {
+		var goingAway bool
 		for c.IsConnectionActive.Status() {
 			// Receive the response
-			if res, err := c.Receive(); err != nil {
+			res, err := c.Receive()
+			if err != nil && (!c.IsConnectionActive.Status() || goingAway || c.leaving.Status()) {
+				// The connection was closed on purpose
 				c.IsConnectionActive.Update(false)
-				c.Logger.Fatal(err)
+				c.Router.CloseAll()
 				return
-			} else if res.Status == mod.OK {
-				if err = c.Send(&mod.Ack{Response: *res}); err != nil {
+			} else if err != nil {
+				c.IsConnectionActive.Update(false)
+				c.Router.CloseAll()
+				errorHandler(c, err)
+				return
+			}
+
+			// The server answers the pending requests, then closes the connection
+			if res.Status == mod.SHUTDOWN && res.Id == "" {
+				goingAway = true
+				c.Logger.Log("the server is shutting down\n")
+				continue
+			}
+
+			// Hand the response to the request waiting for it
+			routed := c.Router.Route(res)
+			if !routed && res.Status != mod.LOG && !c.retryLater(res) && c.Printer != nil {
+				c.Printer(res)
+			}
+
+			if res.Status == mod.OK {
+				if err = c.Send(&mod.Ack{Response: *res}); err != nil && !c.IsConnectionActive.Status() {
+					// The connection was closed on purpose
+					c.Router.CloseAll()
+					return
+				} else if err != nil {
 					c.IsConnectionActive.Update(false)
-					c.Logger.Fatal(err)
+					c.Router.CloseAll()
+					errorHandler(c, err)
 					return
 				}
 			}
@@ -86,20 +130,83 @@ func (c *Client) RecvLoopAsync() {
 	}()
 }
 
+var ErrConnectionLost = errors.New("connection lost before the response arrived")
+
+// Send the request and wait for its final (non LOG) response.
+// The receive loop must be running. Safe for concurrent use.
+// The deadline of the context is sent along, and the server is asked to
+// cancel the request when the context is done first. Rate limited
+// requests are sent again after the delay hinted by the server.
+func (c *Client) Call(ctx context.Context, req mod.Request) (*mod.ResponseModel, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := c.call(ctx, req)
+		if err != nil || !c.mayRetry(res, attempt) {
+			return res, err
+		}
+
+		wait := time.Millisecond * res.RetryAfter
+		c.Logger.Log(res.Content, ", sending the request again in ", wait, "...\n")
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) call(ctx context.Context, req mod.Request) (*mod.ResponseModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Let the server give up at the same time as the caller
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = (time.Until(deadline) + time.Millisecond - 1) / time.Millisecond
+		if timeout <= 0 {
+			timeout = 1
+		}
+	}
+
+	id, responses, err := c.dispatch(req, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.Router.Close(id)
+			c.abandon(id)
+			return nil, ctx.Err()
+		case res, ok := <-responses:
+			if !ok {
+				return nil, ErrConnectionLost
+			}
+			if res.Status != mod.LOG {
+				return res, nil
+			}
+		}
+	}
+}
+
 func (c *Client) Disconnect() (err error) {
 	if c.Connection == nil {
 		return nil
 	}
 
+	// The server closes the connection after bye
+	c.leaving.Update(true)
 	if err = c.Send(&mod.Bye{}); err != nil {
 		return err
 	}
 
+	// Stop the receive loop before the connection goes away
+	c.IsConnectionActive.Update(false)
 	if err = c.Close(); err != nil {
 		return err
 	}
 
-	c.IsConnectionActive.Update(false)
 	c.Logger.Log("connection closed\n")
 	return nil
 }
@@ -116,12 +223,39 @@ func (c *Client) Close() error {
 	return nil
 }
 
+var ErrServerFull = errors.New("server full")
+var ErrUnauthorized = errors.New("unauthorized")
+var ErrBadName = errors.New("name refused")
+
 func (c *Client) Connect() (err error) {
 	// Assure no other connection is on-going
 	if c.Connection != nil {
 		return fmt.Errorf("connection already established")
 	}
 
+	// Wait longer after each refusal of a full server
+	backoff := c.Settings.FullBackoff
+	for retry := c.Settings.FullRetries; ; {
+		err = c.connect()
+		switch {
+		case errors.Is(err, ErrBadName) && c.Settings.AskName:
+			// Let the user pick another name
+			c.Logger.Log(err, "\n")
+			if c.Settings.AskClientName() != nil {
+				return err
+			}
+		case errors.Is(err, ErrServerFull) && retry > 0:
+			c.Logger.Log(err, ", retrying in ", int(backoff), " ms...\n")
+			time.Sleep(time.Millisecond * backoff)
+			backoff *= 2
+			retry--
+		default:
+			return err
+		}
+	}
+}
+
+func (c *Client) connect() (err error) {
 	// Try to establish the connection multiple times
 	if _, err := c.DialWithRetries(); err != nil {
 		return fmt.Errorf(
@@ -135,21 +269,43 @@ func (c *Client) Connect() (err error) {
 	c.IsConnectionActive.Update(true)
 
 	// Send Salute and check for error
-	if err = c.Send(&mod.Salute{}); err != nil {
-		defer c.Close()
+	if err = c.Send(&mod.Salute{
+		Framing:  c.Settings.Framing,
+		Password: c.Settings.Password,
+		Token:    c.Settings.Token,
+	}); err != nil {
+		defer c.Drop()
 		return err
 	}
 
 	// Receive Salute Response
 	var res *mod.ResponseModel
 	if res, err = c.Receive(); err != nil {
-		defer c.Close()
+		defer c.Drop()
 		return err
 	}
 
 	// Check Salute Status
-	if res.Status != mod.OK {
-		defer c.Close()
+	switch res.Status {
+	case mod.OK:
+		// The server answered with the previous framing, switch now
+		if c.Settings.Framing != "" {
+			if err = c.Codec.SetFraming(c.Settings.Framing); err != nil {
+				defer c.Drop()
+				return err
+			}
+		}
+	case mod.SERVERFULL:
+		defer c.Drop()
+		return fmt.Errorf("%w: %v", ErrServerFull, res.Content)
+	case mod.UNAUTHORIZED:
+		defer c.Drop()
+		return fmt.Errorf("%w: %v", ErrUnauthorized, res.Content)
+	case mod.BADNAME:
+		defer c.Drop()
+		return fmt.Errorf("%w: %v", ErrBadName, res.Content)
+	default:
+		defer c.Drop()
 		return fmt.Errorf("%v", res.Content)
 	}
 
@@ -158,6 +314,13 @@ func (c *Client) Connect() (err error) {
 	return nil
 }
 
+// Close the connection and forget it, so a new one can be established
+func (c *Client) Drop() {
+	c.Close()
+	c.Connection = nil
+	c.IsConnectionActive.Update(false)
+}
+
 func (c *Client) DialWithRetries() (*net.Conn, error) {
 	var (
 		conn net.Conn
@@ -167,14 +330,12 @@ func (c *Client) DialWithRetries() (*net.Conn, error) {
 	for retry := c.Settings.MaxRetries; retry != 0; retry-- {
 		c.Logger.Log("attempts left ", retry, " to connect...\n")
 
-		conn, err = net.Dial(
-			c.Settings.Host.Protocol,
-			c.Settings.Host.Server(),
-		)
+		conn, err = c.Settings.Host.Dial()
 
 		if err == nil {
 			c.Connection = new(net.Conn)
 			(*c.Connection) = conn
+			c.Codec = codec.New(conn, c.Settings.MaxFrameSize)
 			c.Logger.Log("connection established\n")
 			return c.Connection, nil
 		}
@@ -186,16 +347,73 @@ func (c *Client) DialWithRetries() (*net.Conn, error) {
 	return nil, err
 }
 
+// Send the request and open a route for its responses, which are
+// delivered while RecvLoopAsync is running
+func (c *Client) Dispatch(req mod.Request) (string, <-chan *mod.ResponseModel, error) {
+	return c.dispatch(req, 0)
+}
+
+// Dispatch the request, letting the server work on it for at most
+// timeout milliseconds when positive
+func (c *Client) dispatch(
+	req mod.Request,
+	timeout time.Duration,
+) (string, <-chan *mod.ResponseModel, error) {
+	id := c.Router.NextId()
+	responses := c.Router.Open(id)
+
+	if err := c.send(id, req, timeout); err != nil {
+		c.Router.Close(id)
+		return "", nil, err
+	}
+
+	return id, responses, nil
+}
+
 func (c *Client) Send(req mod.Request) (err error) {
+	// Reuse the identifier of correlated requests
+	if cr, ok := req.(mod.Correlated); ok {
+		return c.send(cr.RequestId(), req, 0)
+	}
+	id := c.Router.NextId()
+
+	// Keep the commands until their response, to retry them if needed
+	if req.Type() == mod.COMMAND {
+		c.outbox.keep(id, req)
+	}
+	if err = c.send(id, req, 0); err != nil {
+		c.outbox.forget(id)
+	}
+	return err
+}
+
+// Ask the server to stop working on a request nobody waits for anymore,
+// the answer to the cancellation is dropped as well
+func (c *Client) abandon(id string) {
+	_, responses, err := c.Dispatch(&mod.Cancel{Request: id})
+	if err != nil {
+		c.Logger.Log("could not cancel request ", id, ": ", err, "\n")
+		return
+	}
+
+	go func() {
+		for range responses {
+		}
+	}()
+}
+
+func (c *Client) send(id string, req mod.Request, timeout time.Duration) (err error) {
 	if c.Connection == nil {
 		return fmt.Errorf("connection does not exist")
 	}
 
 	// Map object to json request
 	request := mod.RequestModel{
+		Id:      id,
 		Sender:  c.Settings.ClientName,
 		Content: req.Content(),
 		Type:    req.Type(),
+		Timeout: timeout,
 	}
 
 	// Transform to json
@@ -204,13 +422,19 @@ func (c *Client) Send(req mod.Request) (err error) {
 		return fmt.Errorf("could not convert obj to json: %v", err)
 	}
 
-	// Log the request that is being sent
-	json := string(raw)
-	c.Logger.Log("sending request " + json + "...\n")
+	// Log the request that is being sent, without its credentials
+	logged := raw
+	if request.Type == mod.SALUTE {
+		logged, _ = json.Marshal(request.Redacted())
+	}
+	c.Logger.Log("sending request " + string(logged) + "...\n")
 
 	// Write to server
-	if c.IsConnectionActive.Status() {
-		fmt.Fprintf(*c.Connection, "%s\n", json)
+	if !c.IsConnectionActive.Status() {
+		return fmt.Errorf("connection is not active")
+	}
+	if err = c.Codec.WriteFrame(raw); err != nil {
+		return fmt.Errorf("could not write the request: %v", err)
 	}
 
 	// Return nil
@@ -219,22 +443,20 @@ func (c *Client) Send(req mod.Request) (err error) {
 
 func (c *Client) Receive() (*mod.ResponseModel, error) {
 	// Read message sent by the server
-	reader := bufio.NewReader(*c.Connection)
-
-	// Split by newline
 	c.Logger.Log("waiting for responses...\n")
-	input, err := reader.ReadString('\n')
-	fmt.Printf("(Server) received response %v", input)
+	frame, err := c.Codec.ReadFrame()
 
 	// Check for errors (ex. if the connection is still on-going)
 	if err != nil {
-		c.IsConnectionActive.Update(false)
 		return nil, err
 	}
+	if c.Settings.Debug {
+		c.Logger.Log("received response ", string(frame), "\n")
+	}
 
 	// Parse the response
 	var res *mod.ResponseModel = new(mod.ResponseModel)
-	if err = json.Unmarshal([]byte(input), res); err != nil {
+	if err = json.Unmarshal(frame, res); err != nil {
 		return nil, err
 	}
 
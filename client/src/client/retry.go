@@ -0,0 +1,74 @@
+package client
+
+import (
+	mod "aio/common/src/model"
+	"sync"
+	"time"
+)
+
+// Commands sent without waiting for their response, kept until it
+// arrives so they can be sent again when the server is rate limiting
+type outbox struct {
+	requests map[string]*outgoing
+	Mutex    sync.Mutex
+}
+
+type outgoing struct {
+	req      mod.Request
+	attempts int
+}
+
+func (o *outbox) keep(id string, req mod.Request) {
+	o.Mutex.Lock()
+	if o.requests == nil {
+		o.requests = make(map[string]*outgoing)
+	}
+	o.requests[id] = &outgoing{req: req}
+	o.Mutex.Unlock()
+}
+
+func (o *outbox) forget(id string) {
+	o.Mutex.Lock()
+	delete(o.requests, id)
+	o.Mutex.Unlock()
+}
+
+// Check if the rate limited response should be retried after its hint,
+// given the attempts already made
+func (c *Client) mayRetry(res *mod.ResponseModel, attempts int) bool {
+	if res.Status != mod.RATELIMITED || res.RetryAfter <= 0 {
+		return false
+	}
+	if attempts >= c.Settings.RateLimitRetries {
+		return false
+	}
+	return c.Settings.MaxRetryAfter <= 0 || res.RetryAfter <= c.Settings.MaxRetryAfter
+}
+
+// Send a rate limited command again once the server allows it, under the
+// same id. Reports false for the final responses, which are not retried.
+func (c *Client) retryLater(res *mod.ResponseModel) bool {
+	c.outbox.Mutex.Lock()
+	out, ok := c.outbox.requests[res.Id]
+	if ok && !c.mayRetry(res, out.attempts) {
+		delete(c.outbox.requests, res.Id)
+		ok = false
+	} else if ok {
+		out.attempts++
+	}
+	c.outbox.Mutex.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	wait := time.Millisecond * res.RetryAfter
+	c.Logger.Log(res.Content, ", sending request ", res.Id, " again in ", wait, "...\n")
+	time.AfterFunc(wait, func() {
+		if err := c.send(res.Id, out.req, 0); err != nil {
+			c.outbox.forget(res.Id)
+			c.Logger.Log("could not send request ", res.Id, " again: ", err, "\n")
+		}
+	})
+	return true
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	mod "aio/common/src/model"
+	"strconv"
+	"sync"
+)
+
+// Capacity of the channel on which the responses of a request are delivered
+const RouteCapacity = 8
+
+// Router delivers the responses received from the server to the
+// channel opened by the request that produced them.
+type Router struct {
+	Mutex  sync.Mutex
+	next   uint64
+	routes map[string]chan *mod.ResponseModel
+}
+
+func NewRouter() *Router {
+	return &Router{routes: make(map[string]chan *mod.ResponseModel)}
+}
+
+// Generate a new request identifier, unique for the connection
+func (r *Router) NextId() string {
+	r.Mutex.Lock()
+	r.next++
+	id := strconv.FormatUint(r.next, 10)
+	r.Mutex.Unlock()
+	return id
+}
+
+// Open a route for the responses of the request with the given id
+func (r *Router) Open(id string) <-chan *mod.ResponseModel {
+	ch := make(chan *mod.ResponseModel, RouteCapacity)
+	r.Mutex.Lock()
+	r.routes[id] = ch
+	r.Mutex.Unlock()
+	return ch
+}
+
+// Deliver the response to its request, reporting if a route was found.
+// The route is closed once the final (non LOG) response is delivered.
+func (r *Router) Route(res *mod.ResponseModel) bool {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	ch, ok := r.routes[res.Id]
+	if !ok {
+		return false
+	}
+
+	// Progress messages are dropped when nobody reads them, always
+	// keeping room for the final response
+	if res.Status == mod.LOG {
+		if len(ch) < cap(ch)-1 {
+			ch <- res
+		}
+		return true
+	}
+
+	ch <- res
+	close(ch)
+	delete(r.routes, res.Id)
+	return true
+}
+
+// Stop waiting for the responses of a request
+func (r *Router) Close(id string) {
+	r.Mutex.Lock()
+	if ch, ok := r.routes[id]; ok {
+		close(ch)
+		delete(r.routes, id)
+	}
+	r.Mutex.Unlock()
+}
+
+// Close all the routes, used when the connection is lost
+func (r *Router) CloseAll() {
+	r.Mutex.Lock()
+	for id, ch := range r.routes {
+		close(ch)
+		delete(r.routes, id)
+	}
+	r.Mutex.Unlock()
+}
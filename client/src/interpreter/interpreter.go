@@ -31,6 +31,8 @@ func ParseRequest(request string) (req mod.Request, err error) {
 	case mod.BYE:
 		req = new(mod.Bye)
 		return
+	case mod.CANCEL:
+		return ParseCancelRequest(request)
 	default:
 		return nil, fmt.Errorf("")
 	}
@@ -51,6 +53,12 @@ func ParseCommand(request string) (req *mod.Command, err error) {
 		return ParseSolveCommand(params)
 	case mod.LIST:
 		return ParseListCommand(params)
+	case mod.DESCRIBE:
+		return ParseDescribeCommand(params)
+	case mod.SUBMIT:
+		return ParseSubmitCommand(params)
+	case mod.STATUS, mod.RESULT:
+		return ParseJobCommand(verb, params)
 	default:
 		return nil, fmt.Errorf("invalid command verb")
 	}
@@ -66,7 +74,7 @@ func ParseListCommand(params []string) (c *mod.Command, err error) {
 	entity := params[0]
 
 	// Validate content of the params
-	validEntities := regexp.MustCompile(`^(clients)$`)
+	validEntities := regexp.MustCompile(`^(clients|problems|jobs)$`)
 	if !validEntities.MatchString(entity) {
 		return nil, fmt.Errorf("%v cannot be listed", entity)
 	}
@@ -82,40 +90,147 @@ func ParseListCommand(params []string) (c *mod.Command, err error) {
 	return c, nil
 }
 
+func ParseDescribeCommand(params []string) (c *mod.Command, err error) {
+	// Validate the params
+	if len(params) != 1 {
+		return nil, fmt.Errorf("only one argument should be provided")
+	}
+
+	// Bundle the command
+	c = new(mod.Command)
+	c.Verb = mod.DESCRIBE
+	c.Args = mod.DescribeCommand{
+		Problem: params[0],
+	}
+
+	// Return the result
+	return c, nil
+}
+
+// Parse "status <job>" and "result <job>"
+func ParseJobCommand(verb string, params []string) (c *mod.Command, err error) {
+	// Validate the params
+	if len(params) != 1 {
+		return nil, fmt.Errorf("only one argument should be provided")
+	}
+
+	// Bundle the command
+	c = new(mod.Command)
+	c.Verb = verb
+	c.Args = mod.JobCommand{
+		Job: params[0],
+	}
+
+	// Return the result
+	return c, nil
+}
+
+// Submit takes the same arguments as solve, the problems are solved as a job
+func ParseSubmitCommand(params []string) (c *mod.Command, err error) {
+	if c, err = ParseSolveCommand(params); err != nil {
+		return nil, err
+	}
+
+	c.Verb = mod.SUBMIT
+	return c, nil
+}
+
 func ParseSolveCommand(params []string) (c *mod.Command, err error) {
+	// Batch of problem:array pairs
+	if IsSolvePairs(params) {
+		return ParseSolvePairs(params)
+	}
+
 	// Validate the params
-	if len(params) != 2 {
-		return nil, fmt.Errorf("two arguments should be provided")
+	if len(params) < 2 {
+		return nil, fmt.Errorf("at least two arguments should be provided")
 	}
 
 	// Extract the params
-	rawArray := params[1]
+	rawArrays := params[1:]
 	problem := params[0]
 
-	// Validate the format of the array
-	arrayPattern := regexp.MustCompile(`^\[((([^\s,]+,)+)?[^\s,]+)?\]$`)
-	if !arrayPattern.MatchString(rawArray) {
-		return nil, fmt.Errorf("invalid array format")
+	// Parse the arrays
+	arrays := make([][]interface{}, len(rawArrays))
+	for i, rawArray := range rawArrays {
+		if arrays[i], err = ParseArray(rawArray); err != nil {
+			return nil, err
+		}
 	}
 
-	// Parse the array
-	var arr []interface{}
-	if err = json.Unmarshal([]byte(rawArray), &arr); err != nil {
-		return nil, err
+	// Bundle the command
+	c = new(mod.Command)
+	c.Verb = mod.SOLVE
+
+	// Several arrays make a batch of the same problem
+	if len(arrays) == 1 {
+		c.Args = mod.SolveCommand{
+			Problem: problem,
+			Array: arrays[0],
+		}
+	} else {
+		batch := make([]mod.SolveItem, len(arrays))
+		for i, arr := range arrays {
+			batch[i] = mod.SolveItem{Array: arr}
+		}
+		c.Args = mod.SolveCommand{
+			Problem: problem,
+			Batch: batch,
+		}
+	}
+
+	// Return the result
+	return c, nil
+}
+
+// Check if all params look like problem:array
+func IsSolvePairs(params []string) bool {
+	pair := regexp.MustCompile(`^[^\s:\[]+:\[.*\]$`)
+	for _, param := range params {
+		if !pair.MatchString(param) {
+			return false
+		}
+	}
+	return len(params) != 0
+}
+
+func ParseSolvePairs(params []string) (c *mod.Command, err error) {
+	// Split each pair into its problem and array
+	batch := make([]mod.SolveItem, len(params))
+	for i, param := range params {
+		values := strings.SplitN(param, ":", 2)
+		batch[i].Problem = values[0]
+		if batch[i].Array, err = ParseArray(values[1]); err != nil {
+			return nil, err
+		}
 	}
 
 	// Bundle the command
 	c = new(mod.Command)
 	c.Verb = mod.SOLVE
 	c.Args = mod.SolveCommand{
-		Problem: problem,
-		Array: arr,
+		Batch: batch,
 	}
 
 	// Return the result
 	return c, nil
 }
 
+func ParseArray(rawArray string) (arr []interface{}, err error) {
+	// Validate the format of the array
+	arrayPattern := regexp.MustCompile(`^\[((([^\s,]+,)+)?[^\s,]+)?\]$`)
+	if !arrayPattern.MatchString(rawArray) {
+		return nil, fmt.Errorf("invalid array format")
+	}
+
+	// Parse the array
+	if err = json.Unmarshal([]byte(rawArray), &arr); err != nil {
+		return nil, err
+	}
+
+	return arr, nil
+}
+
 func GetRequestType(request string) (string, error) {
 	// Validate and return the type literal as string
 	switch {
@@ -123,6 +238,8 @@ func GetRequestType(request string) (string, error) {
 		return mod.BYE, nil
 	case IsSaluteRequest(request):
 		return mod.SALUTE, nil
+	case IsCancelRequest(request):
+		return mod.CANCEL, nil
 	case IsCommandRequest(request):
 		return mod.COMMAND, nil
 	default:
@@ -130,6 +247,16 @@ func GetRequestType(request string) (string, error) {
 	}
 }
 
+// Parse "cancel <request-id>", the id is shown by the log of the request
+func ParseCancelRequest(request string) (req *mod.Cancel, err error) {
+	values := strings.Fields(request)
+	if len(values) != 2 {
+		return nil, fmt.Errorf("the id of the request should be provided")
+	}
+
+	return &mod.Cancel{Request: values[1]}, nil
+}
+
 func IsCommandRequest(request string) bool {
 	// Compile regex
 	match := regexp.MustCompile(`^\w+(\s+[^\s]+)*$`)
@@ -153,3 +280,11 @@ func IsSaluteRequest(request string) bool {
 	// Validate
 	return match.FindString(request) != ""
 }
+
+func IsCancelRequest(request string) bool {
+	// Compile regex
+	match := regexp.MustCompile(`^cancel(\s+[^\s]+)*$`)
+
+	// Validate
+	return match.FindString(request) != ""
+}
@@ -8,10 +8,12 @@ package main
 import (
 	"aio/client/src/client"
 	"aio/client/src/interpreter"
+	"aio/client/src/render"
+	conf "aio/client/src/settings"
 	mod "aio/common/src/model"
-	"bufio"
+	"fmt"
+	"io"
 	"log"
-	"os"
 )
 
 func main() {
@@ -31,19 +33,31 @@ func main() {
 		c.Logger.Fatal(err, "\n")
 	}
 
+	// Display the answers of the server
+	c.Printer = func(res *mod.ResponseModel) {
+		fmt.Println(render.Render(res))
+	}
+
 	// Listen asynchronously to messages from the server
 	c.RecvLoopAsync()
 
+	// Read buffered input from the user, keeping the lines typed ahead
+	reader := conf.Input
+
 	// Send messages synchronously to the server
 	err := c.SendLoopSync(func(cl *client.Client) (int, error) {
-		// Read buffered input from the user
-		reader := bufio.NewReader(os.Stdin)
-
 		// Read an entire line
-		input, _ := reader.ReadString('\n')
+		input, err := reader.ReadString('\n')
+
+		// Leave when the input ends
+		if err == io.EOF && len(input) == 0 {
+			c.Disconnect()
+			return 1, nil
+		} else if err != nil && err != io.EOF {
+			return -1, err
+		}
 
 		// Interpret the client input
-		var err error
 		var req mod.Request
 		if req, err = interpreter.ParseRequest(input); err != nil {
 			cl.Logger.Log(err, "\n")
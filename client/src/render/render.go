@@ -0,0 +1,179 @@
+package render
+
+import (
+	mod "aio/common/src/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Turn a response of the server into human readable text
+func Render(res *mod.ResponseModel) string {
+	if res.Status != mod.OK {
+		return fmt.Sprintf("%s: %v", res.Status, res.Content)
+	}
+
+	// Recognize the structured contents by their fields
+	content, isObject := res.Content.(map[string]interface{})
+	if !isObject {
+		return fmt.Sprint(res.Content)
+	}
+
+	switch {
+	case content["job"] != nil:
+		var job mod.JobInfo
+		if err := mod.DecodeContent(res.Content, &job); err == nil {
+			return RenderJob(&job)
+		}
+	case content["jobs"] != nil:
+		var list mod.JobList
+		if err := mod.DecodeContent(res.Content, &list); err == nil {
+			return RenderJobs(&list)
+		}
+	case content["problem"] != nil:
+		var sol mod.SolveResult
+		if err := mod.DecodeContent(res.Content, &sol); err == nil {
+			return RenderSolution(&sol)
+		}
+	case content["items"] != nil:
+		var batch mod.BatchResult
+		if err := mod.DecodeContent(res.Content, &batch); err == nil {
+			return RenderBatch(&batch)
+		}
+	case content["problems"] != nil:
+		var catalog mod.ProblemCatalog
+		if err := mod.DecodeContent(res.Content, &catalog); err == nil {
+			return RenderCatalog(&catalog)
+		}
+	case content["examples"] != nil:
+		var info mod.ProblemInfo
+		if err := mod.DecodeContent(res.Content, &info); err == nil {
+			return RenderProblem(&info)
+		}
+	}
+
+	return fmt.Sprint(res.Content)
+}
+
+// Render the items of a batch, one per line
+func RenderBatch(batch *mod.BatchResult) string {
+	lines := make([]string, len(batch.Items))
+	for i, item := range batch.Items {
+		if item.Status == mod.OK {
+			lines[i] = fmt.Sprintf("[%v] %s", i, RenderSolution(&mod.SolveResult{
+				Problem: item.Problem,
+				Result:  item.Result,
+			}))
+		} else {
+			lines[i] = fmt.Sprintf("[%v] problem %s: %s: %s", i, item.Problem, item.Status, item.Error)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render the state of a job on one line
+func RenderJob(job *mod.JobInfo) string {
+	text := fmt.Sprintf("job %s (problem %s): %s", job.Job, job.Problem, job.State)
+	if job.Progress != "" {
+		text += ", " + job.Progress
+	}
+	return text
+}
+
+// List the jobs, one per line
+func RenderJobs(list *mod.JobList) string {
+	if len(list.Jobs) == 0 {
+		return "no jobs"
+	}
+
+	lines := make([]string, len(list.Jobs))
+	for i := range list.Jobs {
+		lines[i] = RenderJob(&list.Jobs[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// List the problems, one per line
+func RenderCatalog(catalog *mod.ProblemCatalog) string {
+	lines := []string{"available problems:"}
+	for i := range catalog.Problems {
+		lines = append(lines, RenderProblem(&catalog.Problems[i]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func RenderProblem(info *mod.ProblemInfo) string {
+	lines := []string{
+		fmt.Sprintf("%s (%s): %s", info.Id, info.Name, info.Description),
+		fmt.Sprintf("\tinput: %s", info.Input),
+	}
+
+	for _, e := range info.Examples {
+		raw, _ := json.Marshal(e.Input)
+		lines = append(lines, fmt.Sprintf(
+			"\texample: solve %s %s => %s",
+			info.Id,
+			string(raw),
+			e.Output,
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Format the result of a problem, falling back to json for unknown ones
+func RenderSolution(sol *mod.SolveResult) (text string) {
+	var err error
+
+	switch sol.Problem {
+	case "1":
+		var r mod.TransposeResult
+		if err = sol.Decode(&r); err == nil {
+			text = strings.Join(r.Words, ", ")
+		}
+	case "2":
+		var r mod.PerfectSquaresResult
+		if err = sol.Decode(&r); err == nil {
+			text = renderPerfectSquares(&r)
+		}
+	case "3":
+		var r mod.ReversedSumResult
+		if err = sol.Decode(&r); err == nil {
+			text = fmt.Sprintf("%v with the sum %v", joinInts(r.Reversed), r.Sum)
+		}
+	case "8":
+		var r mod.PrimeDigitsResult
+		if err = sol.Decode(&r); err == nil {
+			text = fmt.Sprintf("%v digits (%v)", r.Digits, joinInts(r.Primes))
+		}
+	default:
+		err = fmt.Errorf("unknown problem %v", sol.Problem)
+	}
+
+	if err != nil {
+		raw, _ := json.Marshal(sol.Result)
+		return string(raw)
+	}
+	return fmt.Sprintf("problem %s: %s", sol.Problem, text)
+}
+
+func renderPerfectSquares(r *mod.PerfectSquaresResult) string {
+	if len(r.Matches) == 0 {
+		return "no perfect squares"
+	}
+
+	matches := make([]string, len(r.Matches))
+	for i, m := range r.Matches {
+		matches[i] = fmt.Sprintf("%v from %s", m.Number, m.Source)
+	}
+
+	return fmt.Sprintf("%v perfect square(s): %s", r.Count, strings.Join(matches, ", "))
+}
+
+func joinInts(nums []int) string {
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ", ")
+}
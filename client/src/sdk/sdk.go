@@ -0,0 +1,209 @@
+package sdk
+
+import (
+	"aio/client/src/client"
+	mod "aio/common/src/model"
+	"context"
+	"fmt"
+)
+
+// Solver exposes the problems of the server as typed Go methods.
+// The underlying client must be connected and its receive loop running.
+type Solver struct {
+	Client *client.Client
+}
+
+func New(c *client.Client) *Solver {
+	return &Solver{Client: c}
+}
+
+// A number built from the digits of a string which is a perfect square
+type PerfectSquare = mod.PerfectSquare
+
+// Send a solve command and return the content of the successful response
+func (s *Solver) Solve(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+) (interface{}, error) {
+	res, err := s.Client.Call(ctx, &mod.Command{
+		Verb: mod.SOLVE,
+		Args: mod.SolveCommand{
+			Problem: problem,
+			Array:   arr,
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if err = res.Err(); err != nil {
+		return nil, err
+	}
+
+	return res.Content, nil
+}
+
+// Solve a batch of problem/array pairs, returning the result or the
+// error of every item in order. Use Decode on the successful items.
+func (s *Solver) SolveBatch(ctx context.Context, items []mod.SolveItem) ([]mod.BatchItemResult, error) {
+	res, err := s.Client.Call(ctx, &mod.Command{
+		Verb: mod.SOLVE,
+		Args: mod.SolveCommand{Batch: items},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if err = res.Err(); err != nil {
+		return nil, err
+	}
+
+	var batch mod.BatchResult
+	if err = mod.DecodeContent(res.Content, &batch); err != nil {
+		return nil, fmt.Errorf("unexpected result %v: %v", res.Content, err)
+	}
+	return batch.Items, nil
+}
+
+// Submit the problem as a job solved in the background, returning the
+// id of the job
+func (s *Solver) Submit(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+) (string, error) {
+	res, err := s.Client.Call(ctx, &mod.Command{
+		Verb: mod.SUBMIT,
+		Args: mod.SolveCommand{
+			Problem: problem,
+			Array:   arr,
+		},
+	})
+
+	if err != nil {
+		return "", err
+	}
+	if err = res.Err(); err != nil {
+		return "", err
+	}
+
+	var job mod.JobInfo
+	if err = mod.DecodeContent(res.Content, &job); err != nil {
+		return "", fmt.Errorf("unexpected job %v: %v", res.Content, err)
+	}
+	return job.Job, nil
+}
+
+// State of a job submitted by the same client name
+func (s *Solver) Status(ctx context.Context, job string) (*mod.JobInfo, error) {
+	res, err := s.Client.Call(ctx, &mod.Command{
+		Verb: mod.STATUS,
+		Args: mod.JobCommand{Job: job},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if err = res.Err(); err != nil {
+		return nil, err
+	}
+
+	info := new(mod.JobInfo)
+	if err = mod.DecodeContent(res.Content, info); err != nil {
+		return nil, fmt.Errorf("unexpected job %v: %v", res.Content, err)
+	}
+	return info, nil
+}
+
+// Content of the response of a finished job, like Solve returns it.
+// Fails with the pending status while the job is still running.
+func (s *Solver) Result(ctx context.Context, job string) (interface{}, error) {
+	res, err := s.Client.Call(ctx, &mod.Command{
+		Verb: mod.RESULT,
+		Args: mod.JobCommand{Job: job},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if err = res.Err(); err != nil {
+		return nil, err
+	}
+
+	return res.Content, nil
+}
+
+// Problem 1: the i-th word is made of the i-th character of every input word
+func (s *Solver) Transpose(ctx context.Context, words []string) ([]string, error) {
+	var r mod.TransposeResult
+	if err := s.solveInto(ctx, "1", strs(words), &r); err != nil {
+		return nil, err
+	}
+	return r.Words, nil
+}
+
+// Problem 2: the perfect squares formed by the digits of each string
+func (s *Solver) CountPerfectSquares(ctx context.Context, texts []string) ([]PerfectSquare, error) {
+	var r mod.PerfectSquaresResult
+	if err := s.solveInto(ctx, "2", strs(texts), &r); err != nil {
+		return nil, err
+	}
+	return r.Matches, nil
+}
+
+// Problem 3: the sum of the numbers with their digits reversed
+func (s *Solver) SumReversed(ctx context.Context, nums []int) (int, error) {
+	var r mod.ReversedSumResult
+	if err := s.solveInto(ctx, "3", ints(nums), &r); err != nil {
+		return 0, err
+	}
+	return r.Sum, nil
+}
+
+// Problem 8: the total number of digits of the prime numbers, and the primes
+func (s *Solver) CountPrimeDigits(ctx context.Context, nums []int) (int, []int, error) {
+	var r mod.PrimeDigitsResult
+	if err := s.solveInto(ctx, "8", ints(nums), &r); err != nil {
+		return 0, nil, err
+	}
+	return r.Digits, r.Primes, nil
+}
+
+// Solve the problem and decode its result into v
+func (s *Solver) solveInto(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+	v interface{},
+) error {
+	content, err := s.Solve(ctx, problem, arr)
+	if err != nil {
+		return err
+	}
+
+	var sol mod.SolveResult
+	if err = mod.DecodeContent(content, &sol); err != nil {
+		return fmt.Errorf("unexpected result %v: %v", content, err)
+	}
+	if err = sol.Decode(v); err != nil {
+		return fmt.Errorf("unexpected result %v: %v", sol.Result, err)
+	}
+	return nil
+}
+
+func strs(values []string) []interface{} {
+	arr := make([]interface{}, len(values))
+	for i, v := range values {
+		arr[i] = v
+	}
+	return arr
+}
+
+func ints(values []int) []interface{} {
+	arr := make([]interface{}, len(values))
+	for i, v := range values {
+		arr[i] = v
+	}
+	return arr
+}
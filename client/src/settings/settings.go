@@ -2,6 +2,7 @@ package settings
 
 import (
 	"aio/common/src/host"
+	"aio/common/src/terminal"
 	"bufio"
 	"encoding/json"
 	"fmt"
@@ -9,17 +10,33 @@ import (
 	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Standard input shared by the prompts of the client, so the lines typed
+// ahead are not lost between them
+var Input = bufio.NewReader(os.Stdin)
+
 type ClientSettings struct {
 	Timeout            time.Duration     `json:"connectionTimeout"`
 	MaxRetries         int               `json:"maxRetries"`
+	FullRetries        int               `json:"serverFullRetries"`
+	FullBackoff        time.Duration     `json:"serverFullBackoff"`
 	Host               host.HostSettings `json:"host"`
 	ClientName         string            `json:"clientName"`
 	DefaultNameAllowed bool              `json:"defaultNameAllowed"`
 	MaxRngValue        int               `json:"maxRngValue"`
 	AskName            bool              `json:"askName"`
+	Framing            string            `json:"framing"`
+	MaxFrameSize       int               `json:"maxFrameSize"`
+	Password           string            `json:"password"`
+	Token              string            `json:"token"`
+	AskPassword        bool              `json:"askPassword"`
+	RateLimitRetries   int               `json:"rateLimitRetries"`
+	MaxRetryAfter      time.Duration     `json:"maxRetryAfter"`
+	Debug              bool              `json:"debug"`
+	defaultName        string
 }
 
 // Initialize the settings of the client from the config file.
@@ -37,12 +54,43 @@ func ReadSettings(configFilePath string) (*ClientSettings, error) {
 		return nil, fmt.Errorf("could not convert the json to obj: %v", err)
 	}
 
+	// A client certificate already names the client
+	if tls := clientSettings.Host.TLS; tls != nil && tls.Cert != "" {
+		if clientSettings.ClientName, err = tls.Identity(); err != nil {
+			return nil, err
+		}
+		clientSettings.AskName = false
+		return clientSettings, nil
+	}
+
+	clientSettings.defaultName = clientSettings.ClientName
 	clientSettings.getClientName()
+	clientSettings.getPassword()
 	return clientSettings, nil
 }
 
-// Read a client name from stdin or generate one
-func (clientSettings *ClientSettings) getClientName() string {
+// Read the password from stdin when no credentials are configured
+func (clientSettings *ClientSettings) getPassword() string {
+	if !clientSettings.AskPassword ||
+		clientSettings.Password != "" || clientSettings.Token != "" {
+		return clientSettings.Password
+	}
+
+	fmt.Print("Enter the password of ", clientSettings.ClientName, ": ")
+	clientSettings.Password, _ = terminal.ReadSecret(Input)
+	return clientSettings.Password
+}
+
+// Ask the user for another name, after the server refused the previous
+// one. Fails once the input ends.
+func (clientSettings *ClientSettings) AskClientName() error {
+	_, err := clientSettings.getClientName()
+	return err
+}
+
+// Read a client name from stdin or generate one, keeping the previous
+// name when the input ends
+func (clientSettings *ClientSettings) getClientName() (string, error) {
 	// Create random number generator
 	seed := rand.NewSource(time.Now().UnixNano())
 	rng := rand.New(seed)
@@ -50,17 +98,20 @@ func (clientSettings *ClientSettings) getClientName() string {
 	// Forced name generation
 	if !clientSettings.AskName {
 		randomValue := strconv.Itoa(rng.Intn(clientSettings.MaxRngValue))
-		clientSettings.ClientName = clientSettings.ClientName + randomValue
-		return clientSettings.ClientName
+		clientSettings.ClientName = clientSettings.defaultName + randomValue
+		return clientSettings.ClientName, nil
 	}
 
 	// Open communication with the user
-	scanner := bufio.NewScanner(os.Stdin)
 	fmt.Print("Enter a client name: ")
 
 	// Interact with the user
-	for scanner.Scan() {
-		input := scanner.Text()
+	for {
+		input, err := Input.ReadString('\n')
+		input = strings.TrimRight(input, "\r\n")
+		if err != nil && len(input) == 0 {
+			return clientSettings.ClientName, err
+		}
 
 		if len(input) != 0 {
 			clientSettings.ClientName = input
@@ -69,7 +120,7 @@ func (clientSettings *ClientSettings) getClientName() string {
 
 		if clientSettings.DefaultNameAllowed {
 			randomValue := strconv.Itoa(rng.Intn(clientSettings.MaxRngValue))
-			clientSettings.ClientName = clientSettings.ClientName + randomValue
+			clientSettings.ClientName = clientSettings.defaultName + randomValue
 			break
 		}
 
@@ -77,5 +128,5 @@ func (clientSettings *ClientSettings) getClientName() string {
 		fmt.Print("Enter a client name: ")
 	}
 
-	return clientSettings.ClientName
+	return clientSettings.ClientName, nil
 }
@@ -0,0 +1,189 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Framing modes
+const (
+	LINE   = "line"   // Messages are terminated by a newline
+	LENGTH = "length" // Messages are preceded by their length as 4 bytes, big endian
+)
+
+// Used when no maximum frame size is configured
+const DefaultMaxFrameSize = 1 << 20
+
+var ErrFrameTooLarge = errors.New("frame too large")
+
+func IsFraming(framing string) bool {
+	return framing == LINE || framing == LENGTH
+}
+
+// Raised when a complete frame was read but does not hold valid json,
+// the connection itself can still be used
+type MalformedError struct {
+	Err error
+}
+
+func (e MalformedError) Error() string {
+	return fmt.Sprintf("malformed message: %v", e.Err)
+}
+
+func (e MalformedError) Unwrap() error {
+	return e.Err
+}
+
+// Codec splits the byte stream of a connection into messages. It keeps
+// a single reader and writer for the whole connection, so bytes buffered
+// past the end of a message are kept for the next one and messages
+// written from several goroutines never interleave.
+type Codec struct {
+	reader       *bufio.Reader
+	writer       *bufio.Writer
+	framing      string
+	MaxFrameSize int
+	Mutex        sync.Mutex
+	readMutex    sync.Mutex
+	writeMutex   sync.Mutex
+}
+
+func New(rw io.ReadWriter, maxFrameSize int) *Codec {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+
+	return &Codec{
+		reader:       bufio.NewReader(rw),
+		writer:       bufio.NewWriter(rw),
+		framing:      LINE,
+		MaxFrameSize: maxFrameSize,
+	}
+}
+
+// Read the next message and decode its json into v
+func (c *Codec) ReadMessage(v interface{}) error {
+	frame, err := c.ReadFrame()
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(frame, v); err != nil {
+		return MalformedError{Err: err}
+	}
+	return nil
+}
+
+// Encode v as json and write it as a single message
+func (c *Codec) WriteMessage(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not convert obj to json: %v", err)
+	}
+	return c.WriteFrame(raw)
+}
+
+func (c *Codec) Framing() string {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return c.framing
+}
+
+// Switch the framing of the following messages, in both directions
+func (c *Codec) SetFraming(framing string) error {
+	if !IsFraming(framing) {
+		return fmt.Errorf("unknown framing %q", framing)
+	}
+
+	c.Mutex.Lock()
+	c.framing = framing
+	c.Mutex.Unlock()
+	return nil
+}
+
+// Read the next message
+func (c *Codec) ReadFrame() ([]byte, error) {
+	c.readMutex.Lock()
+	defer c.readMutex.Unlock()
+
+	switch c.Framing() {
+	case LENGTH:
+		return c.readLength()
+	default:
+		return c.readLine()
+	}
+}
+
+func (c *Codec) readLine() ([]byte, error) {
+	var frame []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		if len(frame)+len(chunk) > c.MaxFrameSize+1 {
+			return nil, fmt.Errorf("%w: more than %v bytes", ErrFrameTooLarge, c.MaxFrameSize)
+		}
+		frame = append(frame, chunk...)
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err != nil:
+			return nil, err
+		default:
+			return bytes.TrimSuffix(frame, []byte("\n")), nil
+		}
+	}
+}
+
+func (c *Codec) readLength() ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if uint64(size) > uint64(c.MaxFrameSize) {
+		return nil, fmt.Errorf("%w: %v bytes", ErrFrameTooLarge, size)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// Write a message and flush it to the connection
+func (c *Codec) WriteFrame(frame []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	switch c.Framing() {
+	case LENGTH:
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(frame)))
+		if _, err := c.writer.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := c.writer.Write(frame); err != nil {
+			return err
+		}
+	default:
+		if bytes.IndexByte(frame, '\n') >= 0 {
+			return fmt.Errorf("line frames cannot contain newlines")
+		}
+		if _, err := c.writer.Write(frame); err != nil {
+			return err
+		}
+		if err := c.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return c.writer.Flush()
+}
@@ -0,0 +1,161 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func lengthFrame(payload string) string {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+	return string(header[:]) + payload
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing string
+		max     int
+		input   string
+		want    []string
+		wantErr error
+	}{
+		{"line", LINE, 16, "{}\n[1]\n", []string{"{}", "[1]"}, io.EOF},
+		{"line at the limit", LINE, 4, "abcd\n", []string{"abcd"}, io.EOF},
+		{"line over the limit", LINE, 4, "abcde\n", nil, ErrFrameTooLarge},
+		{"line without end", LINE, 16, "abc", nil, io.EOF},
+		{"line longer than the buffer", LINE, 8192, string(bytes.Repeat([]byte("a"), 5000)) + "\n", []string{string(bytes.Repeat([]byte("a"), 5000))}, io.EOF},
+		{"length", LENGTH, 16, lengthFrame("{}") + lengthFrame("a\nb"), []string{"{}", "a\nb"}, io.EOF},
+		{"length at the limit", LENGTH, 4, lengthFrame("abcd"), []string{"abcd"}, io.EOF},
+		{"length over the limit", LENGTH, 4, lengthFrame("abcde"), nil, ErrFrameTooLarge},
+		{"length truncated", LENGTH, 16, lengthFrame("abcd")[:6], nil, io.ErrUnexpectedEOF},
+		{"empty length frame", LENGTH, 16, lengthFrame(""), []string{""}, io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(bytes.NewBufferString(tt.input), tt.max)
+			if err := c.SetFraming(tt.framing); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				frame, err := c.ReadFrame()
+				if err != nil {
+					t.Fatalf("ReadFrame() error = %v, want %q", err, want)
+				}
+				if string(frame) != want {
+					t.Fatalf("ReadFrame() = %q, want %q", frame, want)
+				}
+			}
+
+			if _, err := c.ReadFrame(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("last ReadFrame() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing string
+		frame   string
+		want    string
+		wantErr bool
+	}{
+		{"line", LINE, `{"a":1}`, "{\"a\":1}\n", false},
+		{"line with a newline", LINE, "a\nb", "", true},
+		{"length", LENGTH, "a\nb", lengthFrame("a\nb"), false},
+		{"empty length frame", LENGTH, "", lengthFrame(""), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := New(&out, 0)
+			if err := c.SetFraming(tt.framing); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.WriteFrame([]byte(tt.frame))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteFrame() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("WriteFrame() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+// The bytes following a salute are already buffered when the framing
+// changes, they must be read with the new framing
+func TestFramingSwitch(t *testing.T) {
+	input := "{\"salute\":1}\n" + lengthFrame(`{"next":2}`) + lengthFrame("x\ny")
+	c := New(bytes.NewBufferString(input), 0)
+
+	var salute map[string]int
+	if err := c.ReadMessage(&salute); err != nil || salute["salute"] != 1 {
+		t.Fatalf("ReadMessage() = %v, %v", salute, err)
+	}
+
+	if err := c.SetFraming(LENGTH); err != nil {
+		t.Fatal(err)
+	}
+	var next map[string]int
+	if err := c.ReadMessage(&next); err != nil || next["next"] != 2 {
+		t.Fatalf("ReadMessage() after the switch = %v, %v", next, err)
+	}
+
+	frame, err := c.ReadFrame()
+	if err != nil || string(frame) != "x\ny" {
+		t.Fatalf("ReadFrame() after the switch = %q, %v", frame, err)
+	}
+}
+
+func TestSetFraming(t *testing.T) {
+	tests := []struct {
+		framing string
+		wantErr bool
+	}{
+		{LINE, false},
+		{LENGTH, false},
+		{"", true},
+		{"chunked", true},
+	}
+
+	for _, tt := range tests {
+		c := New(new(bytes.Buffer), 0)
+		if err := c.SetFraming(tt.framing); (err != nil) != tt.wantErr {
+			t.Errorf("SetFraming(%q) error = %v, wantErr %v", tt.framing, err, tt.wantErr)
+		}
+		if tt.wantErr && c.Framing() != LINE {
+			t.Errorf("SetFraming(%q) changed the framing to %q", tt.framing, c.Framing())
+		}
+	}
+}
+
+func TestReadMessageMalformed(t *testing.T) {
+	c := New(bytes.NewBufferString("{not json\n{\"ok\":true}\n"), 0)
+
+	var v map[string]bool
+	var malformed MalformedError
+	if err := c.ReadMessage(&v); !errors.As(err, &malformed) {
+		t.Fatalf("ReadMessage() error = %v, want a MalformedError", err)
+	}
+
+	// The connection stays usable after a malformed message
+	if err := c.ReadMessage(&v); err != nil || !v["ok"] {
+		t.Fatalf("ReadMessage() after a malformed message = %v, %v", v, err)
+	}
+}
+
+func TestDefaultMaxFrameSize(t *testing.T) {
+	if c := New(new(bytes.Buffer), 0); c.MaxFrameSize != DefaultMaxFrameSize {
+		t.Fatalf("MaxFrameSize = %v, want %v", c.MaxFrameSize, DefaultMaxFrameSize)
+	}
+}
@@ -8,6 +8,8 @@ type HostSettings struct {
 	Protocol string `json:"protocol"`
 	Address  string `json:"address"`
 	Port 	 string `json:"port"`
+	// Plain TCP when not set
+	TLS      *TLSSettings `json:"tls,omitempty"`
 }
 
 func (host *HostSettings) Server() string {
@@ -0,0 +1,155 @@
+package host
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+)
+
+// Files and names used to secure the connections with TLS, the paths
+// are relative to the working directory
+type TLSSettings struct {
+	Cert       string `json:"cert"`       // PEM certificate presented to the peer
+	Key        string `json:"key"`        // PEM private key of the certificate
+	CA         string `json:"ca"`         // PEM certificates trusted to verify the peer
+	ServerName string `json:"serverName"` // Name expected in the server certificate
+	// Refuse the clients without a certificate signed by the CA
+	RequireClientCert bool `json:"requireClientCert"`
+}
+
+func (host *HostSettings) UsesTLS() bool {
+	return host.TLS != nil
+}
+
+// Listen on the address of the host, with TLS when configured
+func (host *HostSettings) Listen() (net.Listener, error) {
+	if !host.UsesTLS() {
+		return net.Listen(host.Protocol, host.Server())
+	}
+
+	config, err := host.TLS.ServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen(host.Protocol, host.Server(), config)
+}
+
+// Connect to the host, verifying its certificate when TLS is configured
+func (host *HostSettings) Dial() (net.Conn, error) {
+	if !host.UsesTLS() {
+		return net.Dial(host.Protocol, host.Server())
+	}
+
+	config, err := host.TLS.ClientConfig(host.Address)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Dial(host.Protocol, host.Server(), config)
+}
+
+// Configuration of a server presenting its certificate. Client
+// certificates are verified against the CA when the clients send one.
+func (t *TLSSettings) ServerConfig() (*tls.Config, error) {
+	if t.Cert == "" || t.Key == "" {
+		return nil, fmt.Errorf("tls needs both a certificate and a key")
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the tls certificate: %v", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if t.CA != "" {
+		if config.ClientCAs, err = loadPool(t.CA); err != nil {
+			return nil, err
+		}
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	if t.RequireClientCert {
+		if t.CA == "" {
+			return nil, fmt.Errorf("requiring client certificates needs a ca")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Common name of the subject of the certificate, the identity of a
+// client authenticated with it
+func (t *TLSSettings) Identity() (string, error) {
+	cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return "", fmt.Errorf("cannot load the tls certificate: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return "", fmt.Errorf("cannot parse the tls certificate: %v", err)
+	}
+	return leaf.Subject.CommonName, nil
+}
+
+// Identity proven by the verified certificate of the peer of the
+// connection, reporting false when it did not present one
+func PeerIdentity(conn net.Conn) (name string, ok bool) {
+	tlsConn, isTLS := conn.(*tls.Conn)
+	if !isTLS {
+		return "", false
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+	return state.PeerCertificates[0].Subject.CommonName, true
+}
+
+// Configuration of a client verifying the certificate of the server
+// against the CA, or the system roots when none is set
+func (t *TLSSettings) ClientConfig(address string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: t.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.ServerName == "" {
+		config.ServerName = address
+	}
+
+	var err error
+	if t.CA != "" {
+		if config.RootCAs, err = loadPool(t.CA); err != nil {
+			return nil, err
+		}
+	}
+
+	// Present a certificate to the servers asking for one
+	if t.Cert != "" || t.Key != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load the tls certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the tls ca: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -1,17 +1,27 @@
 package model
 
+import (
+	"fmt"
+	"time"
+)
+
 // Request Types
 const (
 	COMMAND = "command"
 	SALUTE  = "salute"
 	BYE     = "bye"
 	ACK 	= "ack"
+	CANCEL  = "cancel"
 )
 
 // Command Verbs
 const (
 	SOLVE = "solve"	// Answer problems using the server as the solver
 	LIST  = "list" 	// List various information on the server
+	DESCRIBE = "describe" // Show the details of a problem
+	SUBMIT = "submit" // Solve problems in the background as a job
+	STATUS = "status" // Show the state of a job
+	RESULT = "result" // Retrieve the response of a finished job
 )
 
 // Status Codes
@@ -20,14 +30,47 @@ const (
 	UNREGISTERED   = "unregistered"
 	BADREQUEST     = "badrequest"
 	BADNAME        = "badname"
+	BADSENDER      = "badsender"
+	UNAUTHORIZED   = "unauthorized"
+	FORBIDDEN      = "forbidden"
+	RATELIMITED    = "ratelimited"
+	SERVERFULL     = "serverfull"
+	SHUTDOWN       = "shutdown"
+	BUSY           = "busy"
+	PENDING        = "pending"
+	CANCELLED      = "cancelled"
+	TIMEOUT        = "timeout"
 	ERROR          = "error"
 	LOG			   = "log"
 	OK             = "ok"
 )
 
 type ResponseModel struct {
+	Id      string      `json:"id,omitempty"`
 	Content interface{} `json:"content"`
 	Status  string      `json:"status"`
+	// Milliseconds to wait before sending a rate limited request again
+	RetryAfter time.Duration `json:"retryAfter,omitempty"`
+}
+
+// Error carried by a response which does not have the OK or LOG status
+type ResponseError struct {
+	Status  string
+	Message string
+}
+
+func (e ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// Return the error described by the response, if any
+func (r *ResponseModel) Err() error {
+	switch r.Status {
+	case OK, LOG:
+		return nil
+	default:
+		return ResponseError{Status: r.Status, Message: fmt.Sprint(r.Content)}
+	}
 }
 
 type Request interface {
@@ -35,10 +78,47 @@ type Request interface {
 	Type() string
 }
 
+// Requests which must reuse an existing identifier instead of a new one
+type Correlated interface {
+	RequestId() string
+}
+
 type RequestModel struct {
+	Id      string      `json:"id,omitempty"`
 	Type    string      `json:"requestType"`
 	Content interface{} `json:"content"`
 	Sender  string      `json:"sender"`
+	// Milliseconds the client is willing to wait for the response
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// Copy of the request safe to log, with the credentials of a salute masked
+func (r RequestModel) Redacted() RequestModel {
+	if r.Type != SALUTE {
+		return r
+	}
+
+	const mask = "***"
+	switch content := r.Content.(type) {
+	case SaluteContent:
+		if content.Password != "" {
+			content.Password = mask
+		}
+		if content.Token != "" {
+			content.Token = mask
+		}
+		r.Content = content
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(content))
+		for k, v := range content {
+			if k == "password" || k == "token" {
+				v = mask
+			}
+			masked[k] = v
+		}
+		r.Content = masked
+	}
+	return r
 }
 
 type Ack struct {
@@ -53,6 +133,11 @@ func (a *Ack) Type() string {
 	return ACK
 }
 
+// Acknowledge a response under the id of the request that produced it
+func (a *Ack) RequestId() string {
+	return a.Response.Id
+}
+
 type Command struct {
 	Verb string      `json:"verb"`
 	Args interface{} `json:"args"`
@@ -61,12 +146,29 @@ type Command struct {
 type SolveCommand struct {
 	Problem string        `json:"problem"`
 	Array   []interface{} `json:"array"`
+	Batch   []SolveItem   `json:"batch,omitempty"`
+}
+
+// An item of a batch, solving the problem of the command when
+// it does not name one
+type SolveItem struct {
+	Problem string        `json:"problem,omitempty"`
+	Array   []interface{} `json:"array"`
+}
+
+// Names a job for the status and result verbs
+type JobCommand struct {
+	Job string `json:"job"`
 }
 
 type ListCommand struct {
 	Entity string `json:"entity"`
 }
 
+type DescribeCommand struct {
+	Problem string `json:"problem"`
+}
+
 func (c *Command) Type() string {
 	return COMMAND
 }
@@ -75,14 +177,33 @@ func (c *Command) Content() interface{} {
 	return *c
 }
 
-type Salute struct{}
+type Salute struct {
+	Framing  string
+	Password string
+	Token    string
+}
+
+// Content of a salute asking for a different framing of the messages,
+// and carrying the credentials of the client
+type SaluteContent struct {
+	Framing  string `json:"framing,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
 
 func (s *Salute) Type() string {
 	return SALUTE
 }
 
 func (s *Salute) Content() interface{} {
-	return ""
+	if s.Framing == "" && s.Password == "" && s.Token == "" {
+		return ""
+	}
+	return SaluteContent{
+		Framing:  s.Framing,
+		Password: s.Password,
+		Token:    s.Token,
+	}
 }
 
 type Bye struct{}
@@ -94,3 +215,20 @@ func (b *Bye) Type() string {
 func (b *Bye) Content() interface{} {
 	return ""
 }
+
+// Abort a request still in progress on the same connection
+type Cancel struct {
+	Request string
+}
+
+type CancelContent struct {
+	RequestId string `json:"requestId"`
+}
+
+func (c *Cancel) Type() string {
+	return CANCEL
+}
+
+func (c *Cancel) Content() interface{} {
+	return CancelContent{RequestId: c.Request}
+}
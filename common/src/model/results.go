@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Content of a successful solve response
+type SolveResult struct {
+	Problem string      `json:"problem"`
+	Result  interface{} `json:"result"`
+}
+
+// Decode the result into the structure of the problem,
+// as after transport it is a generic json value
+func (r *SolveResult) Decode(v interface{}) error {
+	return DecodeContent(r.Result, v)
+}
+
+// Decode the generic json content of a response into a structure
+func DecodeContent(content interface{}, v interface{}) error {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Content of a successful batch solve response, in the order of the items
+type BatchResult struct {
+	Items []BatchItemResult `json:"items"`
+}
+
+type BatchItemResult struct {
+	Problem string      `json:"problem"`
+	Status  string      `json:"status"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Problem 1
+type TransposeResult struct {
+	Words []string `json:"words"`
+}
+
+// Problem 2
+type PerfectSquare struct {
+	Number int    `json:"number"`
+	Source string `json:"source"`
+}
+
+type PerfectSquaresResult struct {
+	Count   int             `json:"count"`
+	Matches []PerfectSquare `json:"matches"`
+}
+
+// Problem 3
+type ReversedSumResult struct {
+	Reversed []int `json:"reversed"`
+	Sum      int   `json:"sum"`
+}
+
+// Problem 8
+type PrimeDigitsResult struct {
+	Digits int   `json:"digits"`
+	Primes []int `json:"primes"`
+}
+
+// Description of a problem the server can solve
+type ProblemInfo struct {
+	Id          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Input       string           `json:"input"`
+	Examples    []ProblemExample `json:"examples"`
+}
+
+type ProblemExample struct {
+	Input  []interface{} `json:"input"`
+	Output string        `json:"output"`
+}
+
+// Content of the response to `list problems`
+type ProblemCatalog struct {
+	Problems []ProblemInfo `json:"problems"`
+}
+
+// State of a job, pending until it holds the status of its response
+type JobInfo struct {
+	Job       string     `json:"job"`
+	Problem   string     `json:"problem"`
+	State     string     `json:"state"`
+	Progress  string     `json:"progress,omitempty"`
+	Submitted time.Time  `json:"submitted"`
+	Finished  *time.Time `json:"finished,omitempty"`
+}
+
+// Content of the response to `list jobs`
+type JobList struct {
+	Jobs []JobInfo `json:"jobs"`
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package terminal
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Turn the echo of the terminal off, failing when the standard input is
+// not a terminal
+func hideInput() (restore func(), err error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stty("-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		stty(strings.TrimSpace(state))
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
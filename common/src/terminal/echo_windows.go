@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package terminal
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableEchoInput = 0x4
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// Turn the echo of the console off, failing when the standard input is
+// not a console
+func hideInput() (restore func(), err error) {
+	handle := syscall.Handle(os.Stdin.Fd())
+
+	var mode uint32
+	if err = syscall.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	if ok, _, e := setConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput)); ok == 0 {
+		return nil, e
+	}
+
+	return func() {
+		setConsoleMode.Call(uintptr(handle), uintptr(mode))
+	}, nil
+}
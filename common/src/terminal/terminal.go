@@ -0,0 +1,23 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Read a line of the standard input through the reader, without showing
+// it when the standard input is a terminal
+func ReadSecret(r *bufio.Reader) (string, error) {
+	if restore, err := hideInput(); err == nil {
+		defer func() {
+			restore()
+			// The newline typed was not shown either
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+
+	input, err := r.ReadString('\n')
+	return strings.TrimRight(input, "\r\n"), err
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	mod "aio/common/src/model"
+)
+
+var ErrTooManyJobs = errors.New("too many jobs")
+
+// Job solving a command in the background for its owner
+type Job struct {
+	Id        string
+	Owner     string
+	Problem   string
+	Submitted time.Time
+	finished  time.Time
+	progress  string
+	response  *mod.ResponseModel
+}
+
+// Store keeps the jobs of every client, the finished ones only until
+// their retention expires, so they outlive the session submitting them.
+type Store struct {
+	jobs      map[string]*Job
+	next      int
+	retention time.Duration
+	limit     int
+	Mutex     sync.Mutex
+}
+
+// A limit of 0 or less lets the clients hold any number of jobs
+func New(retention time.Duration, limit int) *Store {
+	return &Store{
+		jobs:      make(map[string]*Job),
+		retention: retention,
+		limit:     limit,
+	}
+}
+
+// Create a pending job for the client
+func (st *Store) Add(owner string, problem string) (*Job, error) {
+	st.Mutex.Lock()
+	defer st.Mutex.Unlock()
+	st.expire()
+
+	if st.limit > 0 && st.count(owner) >= st.limit {
+		return nil, ErrTooManyJobs
+	}
+
+	st.next++
+	job := &Job{
+		Id:        strconv.Itoa(st.next),
+		Owner:     owner,
+		Problem:   problem,
+		Submitted: time.Now(),
+		progress:  "job submitted",
+	}
+	st.jobs[job.Id] = job
+	return job, nil
+}
+
+// Find a job of the client, the jobs of other clients are not visible
+func (st *Store) Get(owner string, id string) (*Job, bool) {
+	st.Mutex.Lock()
+	defer st.Mutex.Unlock()
+	st.expire()
+
+	job, ok := st.jobs[id]
+	if !ok || job.Owner != owner {
+		return nil, false
+	}
+	return job, true
+}
+
+// List the jobs of the client in the order they were submitted
+func (st *Store) List(owner string) []*Job {
+	st.Mutex.Lock()
+	defer st.Mutex.Unlock()
+	st.expire()
+
+	list := make([]*Job, 0)
+	for _, job := range st.jobs {
+		if job.Owner == owner {
+			list = append(list, job)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Submitted.Before(list[j].Submitted)
+	})
+	return list
+}
+
+// Remember the latest progress of a pending job
+func (st *Store) Progress(job *Job, progress string) {
+	st.Mutex.Lock()
+	job.progress = progress
+	st.Mutex.Unlock()
+}
+
+// Keep the response of the job until it is retrieved or expires
+func (st *Store) Finish(job *Job, res *mod.ResponseModel) {
+	st.Mutex.Lock()
+	job.response = res
+	job.finished = time.Now()
+	st.Mutex.Unlock()
+}
+
+// Response of the job, nil while it is still pending. A finished job is
+// dropped once its response is retrieved, freeing its place for another.
+func (st *Store) Result(job *Job) *mod.ResponseModel {
+	st.Mutex.Lock()
+	defer st.Mutex.Unlock()
+
+	if job.response != nil {
+		delete(st.jobs, job.Id)
+	}
+	return job.response
+}
+
+// Public state of the job sent to the clients
+func (st *Store) Info(job *Job) mod.JobInfo {
+	st.Mutex.Lock()
+	defer st.Mutex.Unlock()
+
+	info := mod.JobInfo{
+		Job:       job.Id,
+		Problem:   job.Problem,
+		State:     mod.PENDING,
+		Progress:  job.progress,
+		Submitted: job.Submitted,
+	}
+	if job.response != nil {
+		info.State = job.response.Status
+		info.Progress = ""
+		finished := job.finished
+		info.Finished = &finished
+	}
+	return info
+}
+
+// Drop the finished jobs kept for longer than the retention
+func (st *Store) expire() {
+	now := time.Now()
+	for id, job := range st.jobs {
+		if job.response != nil && now.Sub(job.finished) > st.retention {
+			delete(st.jobs, id)
+		}
+	}
+}
+
+func (st *Store) count(owner string) (n int) {
+	for _, job := range st.jobs {
+		if job.Owner == owner {
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,220 @@
+package limits
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDailyQuota = errors.New("daily quota exceeded")
+	ErrTotalQuota = errors.New("total quota exceeded")
+)
+
+// Buckets kept before the full ones are dropped
+const maxIdleBuckets = 1024
+
+// Days of the quota usage, in local time
+const dayLayout = "2006-01-02"
+
+// Limiter keeps a token bucket per key: each request takes a token and
+// the tokens come back at `rate` per second, up to `burst` of them.
+type Limiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	now     func() time.Time
+	Mutex   sync.Mutex
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// A rate of 0 or less lets every request through
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Report if a token is available for the key, without taking it
+func (l *Limiter) Allow(key string) (ok bool, retryAfter time.Duration) {
+	if l == nil || l.rate <= 0 {
+		return true, 0
+	}
+
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+
+	if b := l.lookup(key); b.tokens < 1 {
+		return false, l.wait(b)
+	}
+	return true, 0
+}
+
+// Take a token for the key, or report how long until one is available
+func (l *Limiter) Take(key string) (ok bool, retryAfter time.Duration) {
+	if l == nil || l.rate <= 0 {
+		return true, 0
+	}
+
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+
+	b := l.lookup(key)
+	if b.tokens < 1 {
+		return false, l.wait(b)
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Give back a token taken for a request which was not served
+func (l *Limiter) Put(key string) {
+	if l == nil || l.rate <= 0 {
+		return
+	}
+
+	l.Mutex.Lock()
+	defer l.Mutex.Unlock()
+
+	if b, exists := l.buckets[key]; exists {
+		now := l.now()
+		b.tokens = math.Min(l.burst, l.refill(b, now)+1)
+		b.last = now
+	}
+}
+
+// Bucket of the key with the tokens it got back since last used
+func (l *Limiter) lookup(key string) *bucket {
+	now := l.now()
+	b, exists := l.buckets[key]
+	if !exists {
+		l.prune(now)
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+	return b
+}
+
+// Time until the bucket holds a whole token
+func (l *Limiter) wait(b *bucket) time.Duration {
+	missing := (1 - b.tokens) / l.rate
+	return time.Duration(math.Ceil(missing * float64(time.Second)))
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// Forget the buckets which filled up again, they behave like new ones
+func (l *Limiter) prune(now time.Time) {
+	if len(l.buckets) < maxIdleBuckets {
+		return
+	}
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Quota counts the problems solved for each key, per day and in total
+type Quota struct {
+	daily int
+	total int
+	usage map[string]*usage
+	now   func() time.Time
+	Mutex sync.Mutex
+}
+
+type usage struct {
+	day   string
+	today int
+	total int
+}
+
+// A limit of 0 or less does not restrict the usage
+func NewQuota(daily int, total int) *Quota {
+	return &Quota{
+		daily: daily,
+		total: total,
+		usage: make(map[string]*usage),
+		now:   time.Now,
+	}
+}
+
+// Count n problems for the key if the quota allows them, otherwise report
+// how long until it does, 0 when it never will
+func (q *Quota) Use(key string, n int) (retryAfter time.Duration, err error) {
+	if q == nil || q.daily <= 0 && q.total <= 0 {
+		return 0, nil
+	}
+
+	q.Mutex.Lock()
+	defer q.Mutex.Unlock()
+
+	now := q.now()
+	day := now.Format(dayLayout)
+
+	u, exists := q.usage[key]
+	if !exists {
+		u = new(usage)
+		q.usage[key] = u
+	}
+	if u.day != day {
+		u.day, u.today = day, 0
+	}
+
+	if q.total > 0 && u.total+n > q.total {
+		return 0, ErrTotalQuota
+	}
+	if q.daily > 0 && n > q.daily {
+		return 0, ErrDailyQuota
+	}
+	if q.daily > 0 && u.today+n > q.daily {
+		year, month, date := now.Date()
+		midnight := time.Date(year, month, date+1, 0, 0, 0, 0, now.Location())
+		return midnight.Sub(now), ErrDailyQuota
+	}
+
+	u.today += n
+	u.total += n
+	return 0, nil
+}
+
+// Give back n problems counted by Use which were not solved
+func (q *Quota) Refund(key string, n int) {
+	if q == nil || q.daily <= 0 && q.total <= 0 {
+		return
+	}
+
+	q.Mutex.Lock()
+	defer q.Mutex.Unlock()
+
+	u, exists := q.usage[key]
+	if !exists {
+		return
+	}
+	if u.total -= n; u.total < 0 {
+		u.total = 0
+	}
+
+	// The usage of a past day was already reset
+	if u.day == q.now().Format(dayLayout) {
+		if u.today -= n; u.today < 0 {
+			u.today = 0
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package limits
+
+import (
+	"testing"
+	"time"
+)
+
+// Clock advanced by hand
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func TestLimiter(t *testing.T) {
+	type step struct {
+		after     time.Duration // Time passed since the previous step
+		wantOK    bool
+		wantRetry time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []step
+	}{
+		{"burst then empty", 1, 2, []step{
+			{0, true, 0},
+			{0, true, 0},
+			{0, false, time.Second},
+		}},
+		{"refill", 2, 1, []step{
+			{0, true, 0},
+			{0, false, 500 * time.Millisecond},
+			{250 * time.Millisecond, false, 250 * time.Millisecond},
+			{250 * time.Millisecond, true, 0},
+		}},
+		{"refill stops at the burst", 10, 2, []step{
+			{0, true, 0},
+			{0, true, 0},
+			{time.Hour, true, 0},
+			{0, true, 0},
+			{0, false, 100 * time.Millisecond},
+		}},
+		{"burst of at least one", 1, 0, []step{
+			{0, true, 0},
+			{0, false, time.Second},
+		}},
+		{"disabled", 0, 1, []step{
+			{0, true, 0},
+			{0, true, 0},
+			{0, true, 0},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &clock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+			l := New(tt.rate, tt.burst)
+			l.now = c.now
+
+			for i, st := range tt.steps {
+				c.t = c.t.Add(st.after)
+				ok, retry := l.Take("key")
+				if ok != st.wantOK || retry != st.wantRetry {
+					t.Fatalf("step %v: Take() = %v, %v, want %v, %v",
+						i, ok, retry, st.wantOK, st.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterKeys(t *testing.T) {
+	l := New(1, 1)
+	if ok, _ := l.Take("a"); !ok {
+		t.Fatal("first Take(a) refused")
+	}
+	if ok, _ := l.Take("b"); !ok {
+		t.Fatal("Take(b) refused after Take(a)")
+	}
+	if ok, _ := l.Take("a"); ok {
+		t.Fatal("second Take(a) allowed")
+	}
+}
+
+func TestLimiterAllowPut(t *testing.T) {
+	c := &clock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(1, 1)
+	l.now = c.now
+
+	// Allow does not use the token up
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("key"); !ok {
+			t.Fatalf("Allow() #%v refused", i)
+		}
+	}
+
+	l.Take("key")
+	if ok, retry := l.Allow("key"); ok || retry != time.Second {
+		t.Fatalf("Allow() on an empty bucket = %v, %v", ok, retry)
+	}
+
+	// A token given back is available right away, up to the burst
+	l.Put("key")
+	l.Put("key")
+	if ok, _ := l.Take("key"); !ok {
+		t.Fatal("Take() refused after Put()")
+	}
+	if ok, _ := l.Take("key"); ok {
+		t.Fatal("Put() filled the bucket past the burst")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	type step struct {
+		at        string // Local time of the use
+		n         int
+		wantErr   error
+		wantRetry time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		daily int
+		total int
+		steps []step
+	}{
+		{"daily", 3, 0, []step{
+			{"2024-01-01 10:00", 2, nil, 0},
+			{"2024-01-01 11:00", 1, nil, 0},
+			{"2024-01-01 18:00", 1, ErrDailyQuota, 6 * time.Hour},
+		}},
+		{"rollover at midnight", 3, 0, []step{
+			{"2024-01-01 23:59", 3, nil, 0},
+			{"2024-01-01 23:59", 1, ErrDailyQuota, time.Minute},
+			{"2024-01-02 00:00", 3, nil, 0},
+			{"2024-01-02 00:01", 1, ErrDailyQuota, 23*time.Hour + 59*time.Minute},
+		}},
+		{"rollover across months", 1, 0, []step{
+			{"2024-01-31 12:00", 1, nil, 0},
+			{"2024-02-01 12:00", 1, nil, 0},
+		}},
+		{"larger than a day", 3, 0, []step{
+			{"2024-01-01 10:00", 4, ErrDailyQuota, 0},
+			{"2024-01-01 10:00", 3, nil, 0},
+		}},
+		{"total", 0, 3, []step{
+			{"2024-01-01 10:00", 2, nil, 0},
+			{"2024-01-02 10:00", 2, ErrTotalQuota, 0},
+			{"2024-01-02 10:00", 1, nil, 0},
+			{"2024-01-03 10:00", 1, ErrTotalQuota, 0},
+		}},
+		{"total before daily", 2, 3, []step{
+			{"2024-01-01 10:00", 2, nil, 0},
+			{"2024-01-02 10:00", 2, ErrTotalQuota, 0},
+		}},
+		{"disabled", 0, 0, []step{
+			{"2024-01-01 10:00", 1000, nil, 0},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := new(clock)
+			q := NewQuota(tt.daily, tt.total)
+			q.now = c.now
+
+			for i, st := range tt.steps {
+				at, err := time.ParseInLocation("2006-01-02 15:04", st.at, time.Local)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.t = at
+
+				retry, err := q.Use("key", st.n)
+				if err != st.wantErr || retry != st.wantRetry {
+					t.Fatalf("step %v: Use(%v) = %v, %v, want %v, %v",
+						i, st.n, retry, err, st.wantRetry, st.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestQuotaRefund(t *testing.T) {
+	c := &clock{t: time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)}
+	q := NewQuota(2, 3)
+	q.now = c.now
+
+	if _, err := q.Use("key", 2); err != nil {
+		t.Fatal(err)
+	}
+	q.Refund("key", 1)
+	if _, err := q.Use("key", 1); err != nil {
+		t.Fatalf("Use() after Refund() = %v", err)
+	}
+
+	// A refund on the next day gives back only the total
+	c.t = c.t.Add(24 * time.Hour)
+	q.Refund("key", 2)
+	if _, err := q.Use("key", 2); err != nil {
+		t.Fatalf("Use() after a refund of yesterday = %v", err)
+	}
+	c.t = c.t.Add(24 * time.Hour)
+	if _, err := q.Use("key", 2); err != ErrTotalQuota {
+		t.Fatalf("Use() past the total = %v, want %v", err, ErrTotalQuota)
+	}
+}
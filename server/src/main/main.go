@@ -6,13 +6,19 @@
 package main
 
 import (
+	"aio/common/src/codec"
 	mod "aio/common/src/model"
 	"aio/server/src/server"
-	"strings"
-	"bufio"
+	"os/signal"
+	"context"
+	"syscall"
+	"sync"
+	"errors"
+	"time"
 	"fmt"
 	"log"
 	"net"
+	"os"
 )
 
 func main() {
@@ -25,34 +31,87 @@ func main() {
 		log.Fatal("Failed to initialize server: ", err)
 	}
 
+	// Shut down gracefully on SIGINT and SIGTERM
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			time.Millisecond * s.Settings.ShutdownTimeout,
+		)
+		defer cancel()
+
+		if err := s.Shutdown(ctx); err != nil {
+			s.Logger.Log("shutdown incomplete: ", err, "\n")
+		}
+		close(stopped)
+	}()
+
 	// Start handling requests from clients
-	if err = s.Start(handler, errorHandler); err != nil {
+	err = s.Start(handler, errorHandler)
+	if errors.Is(err, server.ErrServerClosed) {
+		<-stopped
+		s.Logger.Log("server stopped\n")
+	} else if err != nil {
 		s.Logger.Fatal("server encountered an err: ", err)
 	}
 }
 
 func handler(s *server.Server, conn net.Conn, e chan error) {
-	for {
-		// Read request from the client
-		message, err := bufio.NewReader(conn).ReadString('\n')
+	// Track the client that registers on this connection
+	session := s.Open(conn)
 
-		// Assure the connection is stil ongoing
-		if err != nil {
+	// Bound the requests processed at once on this connection, and the
+	// ones accepted while waiting for their turn
+	slots := make(chan struct{}, s.MaxInFlight())
+	accepted := make(chan struct{}, s.MaxPending())
+	var pending sync.WaitGroup
+
+	// Deregister the client however the connection ends
+	var reason error
+	defer func() {
+		s.Release(session, reason)
+	}()
+
+	for {
+		// Drop the client if it stays idle for too long
+		if err := s.KeepAlive(session); err != nil {
 			e <- err
-			break
 		}
 
-		// Remove delimiter
-		message = strings.TrimSuffix(message, "\n");
+		// Read request from the client
+		req, err := s.Receive(session)
 
-		// Parse the received request json
-		var req *mod.RequestModel = new(mod.RequestModel)
-		if err = s.Parse(message, req); err != nil {
+		// Answer requests which are not valid json, the connection is fine
+		var malformed codec.MalformedError
+		if errors.As(err, &malformed) {
 			e <- err
+			if err = s.Send(session, server.BadRequest(
+				fmt.Errorf("malformed request: %v", malformed.Err),
+			)); err != nil {
+				reason = s.DropReason(err)
+				e <- reason
+				break
+			}
 			continue
 		}
 
+		// Assure the connection is stil ongoing
+		if err != nil {
+			reason = s.DropReason(err)
+			e <- reason
+			break
+		}
+
 		if req.Type == mod.ACK {
+			// Ignore confirmations sent on behalf of other clients
+			if !session.Owns(req.Sender) {
+				continue
+			}
+
 			// Log the client confirmation of OK messages
 			s.Logger.Log(
 				fmt.Sprintf(
@@ -63,41 +122,157 @@ func handler(s *server.Server, conn net.Conn, e chan error) {
 			continue
 		}
 
-		if req.Type != mod.SALUTE {
-			// Inform the user that the server has received the request
-			s.Logger.Log("received client request\n")
-			if err = s.Send(conn, &mod.ResponseModel{
-				Content: "server has received the request",
-				Status: mod.LOG,
-			}); err != nil {
-				e <- err
+		// Refuse new requests while shutting down
+		done, ok := s.Track()
+		if !ok {
+			res := s.GoingAway()
+			res.Id = req.Id
+			if err = s.Send(session, res); err != nil {
+				reason = s.DropReason(err)
+				e <- reason
 				break
 			}
+			continue
+		}
 
-			// Inform the user that the server is processing the data
-			s.Logger.Log("processing client request\n")
-			if err = s.Send(conn, &mod.ResponseModel{
-				Content: "server is processing the request",
-				Status: mod.LOG,
-			}); err != nil {
-				e <- err
-				break
+		// Commands are answered concurrently, in any order
+		if req.Type == mod.COMMAND {
+			// Answer the commands past the bound right away, the read
+			// loop is the only one adding to it
+			if len(accepted) == cap(accepted) {
+				res := s.TooManyPending()
+				res.Id = req.Id
+				err = s.Send(session, res)
+				done()
+
+				if err != nil {
+					reason = s.DropReason(err)
+					e <- reason
+					break
+				}
+				continue
 			}
+			accepted <- struct{}{}
+
+			// Register the command before reading on, a cancellation
+			// sent right after it must find it
+			ctx, cancel := s.RequestContext(session, req)
+			session.Begin()
+			pending.Add(1)
+			go func(req *mod.RequestModel) {
+				defer func() {
+					if r := recover(); r != nil {
+						e <- fmt.Errorf("request %v crashed: %v", req.Id, r)
+					}
+					cancel()
+					<-accepted
+
+					// The idle timeout starts once the last request ends
+					if session.End() {
+						if err := s.KeepAlive(session); err != nil {
+							e <- err
+						}
+					}
+					pending.Done()
+					done()
+				}()
+
+				// Wait for a slot here, the read loop keeps reading the
+				// cancellations meanwhile
+				select {
+				case slots <- struct{}{}:
+					defer func() { <-slots }()
+				case <-ctx.Done():
+					// Cancelled while waiting, answered without working
+				}
+
+				if _, err := respond(ctx, s, session, req, e); err != nil {
+					e <- s.DropReason(err)
+				}
+			}(req)
+			continue
 		}
 
-		// Process the request
-		var res *mod.ResponseModel
-		if res, err = s.ProcessRequest(req); err != nil {
-			e <- err
+		// Cancellations are answered right away, without waiting for the
+		// commands in progress
+		if req.Type == mod.CANCEL {
+			_, err := respond(context.Background(), s, session, req, e)
+			done()
+
+			if err != nil {
+				reason = s.DropReason(err)
+				e <- reason
+				break
+			}
 			continue
 		}
 
-		if req.Type == mod.BYE && res.Status == mod.OK {
+		// Salute and bye change the session, answer them after the
+		// requests already received
+		pending.Wait()
+		stop, err := respond(context.Background(), s, session, req, e)
+		done()
+
+		if err != nil {
+			reason = s.DropReason(err)
+			e <- reason
+			break
+		} else if stop {
+			return
+		}
+	}
+}
+
+// Process the request and send the response, reporting if the client left
+func respond(
+	ctx context.Context,
+	s *server.Server,
+	session *server.Session,
+	req *mod.RequestModel,
+	e chan error,
+) (stop bool, err error) {
+	if req.Type != mod.SALUTE {
+		// Inform the user that the server has received the request
+		s.Logger.Log("received client request\n")
+		if err = s.Send(session, &mod.ResponseModel{
+			Id: req.Id,
+			Content: "server has received the request",
+			Status: mod.LOG,
+		}); err != nil {
 			return
 		}
 
-		s.Send(conn, res)
+		// Inform the user that the server is processing the data
+		s.Logger.Log("processing client request\n")
+		if err = s.Send(session, &mod.ResponseModel{
+			Id: req.Id,
+			Content: "server is processing the request",
+			Status: mod.LOG,
+		}); err != nil {
+			return
+		}
+	}
+
+	// Process the request
+	var res *mod.ResponseModel
+	if res, err = s.ProcessRequest(ctx, session, req); err != nil {
+		e <- err
+		return false, nil
+	}
+
+	if req.Type == mod.BYE && res.Status == mod.OK {
+		return true, nil
+	}
+
+	if err = s.Send(session, res); err != nil {
+		return
+	}
+
+	// Switch to the framing negotiated at salute
+	if req.Type == mod.SALUTE && res.Status == mod.OK {
+		err = session.ApplyFraming()
 	}
+	return
 }
 
 func errorHandler(s *server.Server, err error) {
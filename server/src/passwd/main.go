@@ -0,0 +1,28 @@
+// Hash a password or token for the user file of the server:
+// go run ./server/src/passwd
+// then type the secret, the hash is printed on the standard output
+
+package main
+
+import (
+	"aio/common/src/terminal"
+	"aio/server/src/users"
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	fmt.Fprint(os.Stderr, "Enter the secret to hash: ")
+	secret, err := terminal.ReadSecret(bufio.NewReader(os.Stdin))
+	if err != nil && secret == "" {
+		log.Fatal("no secret given")
+	}
+
+	hash, err := users.Hash(secret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(hash)
+}
@@ -9,6 +9,7 @@ type ConcurrentMap interface {
 	Keys() []string
 	Delete(string)
 	Add(string)
+	TryAddLimited(string, int) (bool, bool)
 	Size() int
 }
 
@@ -30,6 +31,23 @@ func (pm *PMap) Add(key string) {
 	pm.Mutex.Unlock()
 }
 
+// Add the key only if it is absent and the map holds less than `limit` keys,
+// reporting if it was added and if the limit was reached. A non-positive
+// limit leaves the map unbounded.
+func (pm *PMap) TryAddLimited(key string, limit int) (added bool, full bool) {
+	pm.Mutex.Lock()
+	if _, exists := pm.Map[key]; exists {
+		// Nothing to add
+	} else if limit > 0 && len(pm.Map) >= limit {
+		full = true
+	} else {
+		pm.Map[key] = true
+		added = true
+	}
+	pm.Mutex.Unlock()
+	return
+}
+
 func (pm *PMap) Delete(key string) {
 	pm.Mutex.Lock()
 	delete(pm.Map, key)
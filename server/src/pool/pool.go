@@ -0,0 +1,188 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var ErrQueueFull = errors.New("job queue is full")
+
+// Ticket of a submitted job, following its position in the queue
+type Ticket struct {
+	job       func()
+	positions chan int
+	done      chan struct{}
+	err       error
+}
+
+// Wait for the job to finish, reporting each new queue position.
+// Position 0 means the job left the queue and is being run.
+func (t *Ticket) Wait(onPosition func(int)) error {
+	for {
+		select {
+		case pos := <-t.positions:
+			if onPosition != nil {
+				onPosition(pos)
+			}
+		case <-t.done:
+			return t.err
+		}
+	}
+}
+
+// Closed once the job finished or was withdrawn
+func (t *Ticket) Done() <-chan struct{} {
+	return t.done
+}
+
+// Keep only the latest position for readers which fall behind
+func (t *Ticket) move(pos int) {
+	select {
+	case <-t.positions:
+	default:
+	}
+	t.positions <- pos
+}
+
+// Pool runs the submitted jobs on a fixed number of workers, holding at
+// most `depth` jobs in its queue while the workers are busy.
+type Pool struct {
+	queue []*Ticket
+	depth int
+	Mutex sync.Mutex
+	cond  *sync.Cond // Signalled when a job is queued
+	room  *sync.Cond // Signalled when a job leaves the queue
+}
+
+func New(workers int, depth int) *Pool {
+	p := &Pool{depth: depth}
+	p.cond = sync.NewCond(&p.Mutex)
+	p.room = sync.NewCond(&p.Mutex)
+
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Queue the job, failing right away when the queue is full
+func (p *Pool) Submit(job func()) (*Ticket, error) {
+	p.Mutex.Lock()
+	defer p.Mutex.Unlock()
+
+	if len(p.queue) >= p.depth {
+		return nil, ErrQueueFull
+	}
+	return p.push(job), nil
+}
+
+// Queue the job, waiting for room in the queue when it is full. Fails
+// with the error of the context when it is done first.
+func (p *Pool) SubmitWait(ctx context.Context, job func()) (*Ticket, error) {
+	// Wake the waiting submitter up when the context is done
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.Mutex.Lock()
+			p.room.Broadcast()
+			p.Mutex.Unlock()
+		case <-stop:
+		}
+	}()
+
+	p.Mutex.Lock()
+	defer p.Mutex.Unlock()
+
+	for len(p.queue) >= p.depth {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p.room.Wait()
+	}
+
+	// The room this submitter was woken up for goes to the next one
+	if err := ctx.Err(); err != nil {
+		p.room.Signal()
+		return nil, err
+	}
+	return p.push(job), nil
+}
+
+// Append the job to the queue, the caller holds the lock
+func (p *Pool) push(job func()) *Ticket {
+	t := &Ticket{
+		job:       job,
+		positions: make(chan int, 1),
+		done:      make(chan struct{}),
+	}
+
+	p.queue = append(p.queue, t)
+	t.move(len(p.queue))
+	p.cond.Signal()
+	return t
+}
+
+// Take a job out of the queue before a worker runs it, failing its ticket
+// with err. Reports false when the job already left the queue.
+func (p *Pool) Withdraw(t *Ticket, err error) bool {
+	p.Mutex.Lock()
+	defer p.Mutex.Unlock()
+
+	for i, waiting := range p.queue {
+		if waiting != t {
+			continue
+		}
+
+		// The jobs behind it move up one position
+		copy(p.queue[i:], p.queue[i+1:])
+		p.queue[len(p.queue)-1] = nil
+		p.queue = p.queue[:len(p.queue)-1]
+		for j := i; j < len(p.queue); j++ {
+			p.queue[j].move(j + 1)
+		}
+
+		p.room.Signal()
+
+		t.err = err
+		close(t.done)
+		return true
+	}
+	return false
+}
+
+func (p *Pool) work() {
+	for {
+		p.Mutex.Lock()
+		for len(p.queue) == 0 {
+			p.cond.Wait()
+		}
+
+		// Take the first job, the others move up one position
+		t := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		for i, waiting := range p.queue {
+			waiting.move(i + 1)
+		}
+		p.room.Signal()
+		p.Mutex.Unlock()
+
+		t.move(0)
+		p.run(t)
+	}
+}
+
+// Run the job, a panic fails the job instead of the worker
+func (p *Pool) run(t *Ticket) {
+	defer close(t.done)
+	defer func() {
+		if r := recover(); r != nil {
+			t.err = fmt.Errorf("job crashed: %v", r)
+		}
+	}()
+	t.job()
+}
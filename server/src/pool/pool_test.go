@@ -0,0 +1,200 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Latest position reported to the ticket, -1 when none is pending
+func position(t *Ticket) int {
+	select {
+	case pos := <-t.positions:
+		return pos
+	default:
+		return -1
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	errWithdrawn := errors.New("withdrawn")
+
+	tests := []struct {
+		name     string
+		queued   int
+		withdraw []int
+		want     []int // Positions of the tickets left, -1 when not moved
+	}{
+		{"first", 3, []int{0}, []int{-1, 1, 2}},
+		{"middle", 3, []int{1}, []int{-1, -1, 2}},
+		{"last", 3, []int{2}, []int{-1, -1, -1}},
+		{"two", 4, []int{0, 2}, []int{-1, 1, -1, 2}},
+		{"all", 2, []int{1, 0}, []int{-1, -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without workers the jobs stay in the queue
+			p := New(0, tt.queued)
+			tickets := make([]*Ticket, tt.queued)
+			for i := range tickets {
+				ticket, err := p.Submit(func() {})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pos := position(ticket); pos != i+1 {
+					t.Fatalf("ticket %v queued at position %v", i, pos)
+				}
+				tickets[i] = ticket
+			}
+
+			withdrawn := make(map[int]bool)
+			for _, i := range tt.withdraw {
+				if !p.Withdraw(tickets[i], errWithdrawn) {
+					t.Fatalf("Withdraw(%v) = false", i)
+				}
+				if err := tickets[i].Wait(nil); err != errWithdrawn {
+					t.Fatalf("ticket %v failed with %v", i, err)
+				}
+				withdrawn[i] = true
+			}
+
+			for i, ticket := range tickets {
+				if withdrawn[i] {
+					continue
+				}
+				if pos := position(ticket); pos != tt.want[i] {
+					t.Errorf("ticket %v at position %v, want %v", i, pos, tt.want[i])
+				}
+			}
+			if queued := len(p.queue); queued != tt.queued-len(tt.withdraw) {
+				t.Errorf("%v jobs queued, want %v", queued, tt.queued-len(tt.withdraw))
+			}
+		})
+	}
+}
+
+func TestWithdrawTwice(t *testing.T) {
+	p := New(0, 1)
+	ticket, _ := p.Submit(func() {})
+
+	if !p.Withdraw(ticket, context.Canceled) {
+		t.Fatal("first Withdraw() = false")
+	}
+	if p.Withdraw(ticket, context.Canceled) {
+		t.Fatal("second Withdraw() = true")
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	tests := []struct {
+		name    string
+		depth   int
+		submits int
+		wantErr int // Submits failing with ErrQueueFull
+	}{
+		{"empty", 2, 0, 0},
+		{"room left", 2, 1, 0},
+		{"full", 2, 2, 0},
+		{"over", 2, 5, 3},
+		{"no queue", 0, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(0, tt.depth)
+
+			failed := 0
+			for i := 0; i < tt.submits; i++ {
+				if _, err := p.Submit(func() {}); err == ErrQueueFull {
+					failed++
+				} else if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if failed != tt.wantErr {
+				t.Errorf("%v submits failed, want %v", failed, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     func()
+		wantErr bool
+	}{
+		{"ok", func() {}, false},
+		{"panic", func() { panic("boom") }, true},
+	}
+
+	p := New(1, 1)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket, err := p.Submit(tt.job)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			positions := []int{}
+			err = ticket.Wait(func(pos int) {
+				positions = append(positions, pos)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Wait() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(positions) == 0 || positions[len(positions)-1] != 0 {
+				t.Errorf("positions %v do not end with 0", positions)
+			}
+		})
+	}
+}
+
+func TestSubmitWait(t *testing.T) {
+	release := make(chan struct{})
+	p := New(1, 1)
+
+	// Hold the worker and fill the queue
+	running, _ := p.Submit(func() { <-release })
+	for position(running) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := p.Submit(func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A waiting submit gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.SubmitWait(ctx, func() {}); err != context.DeadlineExceeded {
+		t.Fatalf("SubmitWait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// And gets in once the queue has room again
+	queued := make(chan *Ticket)
+	go func() {
+		ticket, err := p.SubmitWait(context.Background(), func() {})
+		if err != nil {
+			t.Error(err)
+		}
+		queued <- ticket
+	}()
+
+	select {
+	case <-queued:
+		t.Fatal("SubmitWait() returned while the queue was full")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case ticket := <-queued:
+		if err := ticket.Wait(nil); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SubmitWait() still waiting after the queue emptied")
+	}
+}
@@ -1,11 +1,12 @@
 package problems
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"reflect"
-	"strings"
-	"strconv"
+	"time"
+	mod "aio/common/src/model"
 )
 
 /*
@@ -16,12 +17,12 @@ import (
 	Pentru pozitia 0 avem cmtt4 pentru ca sunt alese in ordine caracterele de pozitia 0
 	din fiecare string, deci c din casa, m din masa etc.
 */
-func Problem1(arr []interface{}) (string, error) {
+func Problem1(ctx context.Context, arr []interface{}) (interface{}, error) {
 	// Validate the input
 	if len(arr) == 0 {
-		return "", nil
+		return mod.TransposeResult{Words: []string{}}, nil
 	} else if !IsArrayOfType(arr, "") {
-		return "", ArrTypeMismatchError{"string"};
+		return nil, ArrTypeMismatchError{"string"};
 	}
 
 	// Size of each element in the array
@@ -32,7 +33,7 @@ func Problem1(arr []interface{}) (string, error) {
 		if sz == -1 {
 			sz = len(e.(string))
 		} else if sz != len(e.(string)) {
-			return "", fmt.Errorf("strings are not equal");
+			return nil, fmt.Errorf("strings are not equal");
 		}
 	}
 
@@ -46,6 +47,10 @@ func Problem1(arr []interface{}) (string, error) {
 	// lungimea unui cuvant da numarul de cuvinte noi
 	// Compute the result
 	for i, e := range arr {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		for j, c := range e.(string) {
 			words[j][i] = c
 		}
@@ -57,8 +62,22 @@ func Problem1(arr []interface{}) (string, error) {
 		result[i] = string(e)
 	}
 
-	// Return the compact result
-	return strings.Join(result, ", "), nil
+	// Return the transposed words
+	return mod.TransposeResult{Words: result}, nil
+}
+
+func init() {
+	Register(Problem{
+		Id: "1",
+		Name: "transpose",
+		Description: "the i-th output word is made of the i-th character of every input word",
+		Schema: Schema{Type: STRING, SameLength: true},
+		Examples: []Example{{
+			Input: []interface{}{"casa", "masa", "trei", "tanc", "4321"},
+			Output: "cmtt4, aara3, ssen2, aaic1",
+		}},
+		Solve: Problem1,
+	})
 }
 
 /*
@@ -68,31 +87,49 @@ func Problem1(arr []interface{}) (string, error) {
 	Exemplu: abd4g5, 1sdf6fd, fd2fdsf5 => 2 pătrate perfecte: 16 din 1sdf6fd, 25
 	dinfd2fdsf5
 */
-func Problem2(arr []interface{}) (string, error) {
+func Problem2(ctx context.Context, arr []interface{}) (interface{}, error) {
+	// Allocate memory for matches
+	matches := make([]mod.PerfectSquare, 0)
+
 	if len(arr) == 0 {
-		return "no perfect squares", nil
+		return mod.PerfectSquaresResult{Matches: matches}, nil
 	} else if !IsArrayOfType(arr, "") {
-		return "", ArrTypeMismatchError{"string"}
+		return nil, ArrTypeMismatchError{"string"}
 	}
 
-	// Allocate memory for matches
-	matches := make([]string, 0)
-
 	// Find perfect squares
 	for _, e := range arr {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		if num, isNum := ExtractNum(e.(string)); isNum && IsPerfectSquare(num) {
-			matches = append(matches, fmt.Sprintf("%v from %s", num, e))
+			matches = append(matches, mod.PerfectSquare{
+				Number: num,
+				Source: e.(string),
+			})
 		}
 	}
 
-	// Check if there were no matches
-	if len(matches) == 0 {
-		return "no perfect squares", nil
-	}
+	// Return the matches
+	return mod.PerfectSquaresResult{
+		Count: len(matches),
+		Matches: matches,
+	}, nil
+}
 
-	// Concatenate the results
-	result := strings.Join(matches, ", ")
-	return fmt.Sprintf("%v perfect square(s): %s", len(matches), result), nil
+func init() {
+	Register(Problem{
+		Id: "2",
+		Name: "perfect-squares",
+		Description: "the numbers formed by the digits of each string which are perfect squares",
+		Schema: Schema{Type: STRING},
+		Examples: []Example{{
+			Input: []interface{}{"abd4g5", "1sdf6fd", "fd2fdsf5"},
+			Output: "2 perfect square(s): 16 from 1sdf6fd, 25 from fd2fdsf5",
+		}},
+		Solve: Problem2,
+	})
 }
 
 func IsPerfectSquare(num int) bool {
@@ -122,24 +159,44 @@ Serverul răspunde către client cu suma numerelor array-ului format prin invers
 fiecărui element din array-ul inițial.
 Exemplu: 12, 13, 14 => 21, 31, 41 cu suma 93
 */
-func Problem3(arr []interface{}) (string, error) {
+func Problem3(ctx context.Context, arr []interface{}) (interface{}, error) {
 	if  len(arr) == 0 {
-		return "0", nil
+		return mod.ReversedSumResult{Reversed: []int{}}, nil
 	} else {
 		for _, e := range arr {
 			if reflect.TypeOf(e) != reflect.TypeOf(0.) ||
 				float64(int(e.(float64))) != e.(float64) {
-				return "", ArrTypeMismatchError{Type:"int"}
+				return nil, ArrTypeMismatchError{Type:"int"}
 			}
 		}
 	}
 
 	var sum int
-	for _, num := range arr {
-		sum += ReverseNum(int(num.(float64)))
+	reversed := make([]int, len(arr))
+	for i, num := range arr {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		reversed[i] = ReverseNum(int(num.(float64)))
+		sum += reversed[i]
 	}
 
-	return strconv.Itoa(sum), nil
+	return mod.ReversedSumResult{Reversed: reversed, Sum: sum}, nil
+}
+
+func init() {
+	Register(Problem{
+		Id: "3",
+		Name: "sum-reversed",
+		Description: "the sum of the numbers obtained by reversing the digits of each number",
+		Schema: Schema{Type: INT},
+		Examples: []Example{{
+			Input: []interface{}{12, 13, 14},
+			Output: "21, 31, 41 with the sum 93",
+		}},
+		Solve: Problem3,
+	})
 }
 
 func ReverseNum(num int) (rev int) {
@@ -154,28 +211,53 @@ func ReverseNum(num int) (rev int) {
 Server-ul returnează numărul total de cifre al tuturor numerelor prime din șir.
 Exemplu: Pentru: 23, 17, 15, 3, 18 => 5 cifre (nr 23, 17, 3)
 */
-func Problem8(arr []interface{}) (string, error) {
+func Problem8(ctx context.Context, arr []interface{}) (interface{}, error) {
 	if len(arr) == 0 {
-		return "0 digits ()", nil
+		return mod.PrimeDigitsResult{Primes: []int{}}, nil
 	} else {
 		for _, e := range arr {
 			if reflect.TypeOf(e) != reflect.TypeOf(0.) ||
 				float64(int(e.(float64))) != e.(float64) {
-				return "", ArrTypeMismatchError{Type:"int"}
+				return nil, ArrTypeMismatchError{Type:"int"}
 			} else if e.(float64) < 0 {
-				return "", fmt.Errorf("cannot use negative numbers")
+				return nil, fmt.Errorf("cannot use negative numbers")
 			}
 		}
 	}
 
 	var total int
+	primes := make([]int, 0)
 	for _, num := range arr {
-		if IsPrimeNum(int(num.(float64))) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		prime, err := IsPrimeNumContext(ctx, int(num.(float64)))
+		if err != nil {
+			return nil, err
+		}
+		if prime {
 			total += CountDigits(int(num.(float64)))
+			primes = append(primes, int(num.(float64)))
 		}
 	}
 
-	return fmt.Sprintf("%v", total), nil
+	return mod.PrimeDigitsResult{Digits: total, Primes: primes}, nil
+}
+
+func init() {
+	Register(Problem{
+		Id: "8",
+		Name: "prime-digits",
+		Description: "the total number of digits of the prime numbers",
+		Schema: Schema{Type: NATURAL},
+		Examples: []Example{{
+			Input: []interface{}{23, 17, 15, 3, 18},
+			Output: "5 digits (23, 17, 3)",
+		}},
+		Timeout: 30 * time.Second,
+		Solve: Problem8,
+	})
 }
 
 func CountDigits(num int) int {
@@ -189,17 +271,29 @@ func CountDigits(num int) int {
 }
 
 func IsPrimeNum(num int) bool {
+	prime, _ := IsPrimeNumContext(context.Background(), num)
+	return prime
+}
+
+// Trial division which gives up once the context is done, large numbers
+// need millions of divisions
+func IsPrimeNumContext(ctx context.Context, num int) (bool, error) {
 	if num < 2 {
-		return false
+		return false, nil
 	}
 
 	for i := 2; float64(i) <= math.Sqrt(float64(num)); i++ {
+		if i % 4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+		}
 		if num % i == 0 {
-			return false
+			return false, nil
 		}
 	}
 
-	return true
+	return true, nil
 }
 
 // Check if the array has all elements of type `typeCheck`
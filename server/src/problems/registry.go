@@ -0,0 +1,221 @@
+package problems
+
+import (
+	mod "aio/common/src/model"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Element types accepted by the problems
+const (
+	STRING  = "string"
+	INT     = "int"
+	NATURAL = "natural"
+)
+
+// Solvers should return the error of the context as soon as it is done
+type Solver func(context.Context, []interface{}) (interface{}, error)
+
+// Time given to the problems which do not set their own timeout
+const DefaultTimeout = 10 * time.Second
+
+// Constraints the input array must satisfy before reaching the solver
+type Schema struct {
+	Type       string `json:"type"`
+	MinLen     int    `json:"minLen,omitempty"`
+	MaxLen     int    `json:"maxLen,omitempty"`
+	SameLength bool   `json:"sameLength,omitempty"`
+}
+
+type Example struct {
+	Input  []interface{} `json:"input"`
+	Output string        `json:"output"`
+}
+
+type Problem struct {
+	Id          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Schema      Schema        `json:"schema"`
+	Examples    []Example     `json:"examples"`
+	Timeout     time.Duration `json:"-"`
+	Solve       Solver        `json:"-"`
+}
+
+var registry = struct {
+	Mutex    sync.RWMutex
+	problems map[string]*Problem
+}{problems: make(map[string]*Problem)}
+
+// Make a problem available to the server. Meant to be called from the
+// init function of the file defining the solver.
+func Register(p Problem) {
+	registry.Mutex.Lock()
+	defer registry.Mutex.Unlock()
+
+	if p.Solve == nil {
+		panic(fmt.Sprintf("problems: register problem %s without solver", p.Id))
+	}
+	if _, exists := registry.problems[p.Id]; exists {
+		panic(fmt.Sprintf("problems: register problem %s twice", p.Id))
+	}
+
+	registry.problems[p.Id] = &p
+}
+
+func Lookup(id string) (*Problem, bool) {
+	registry.Mutex.RLock()
+	p, ok := registry.problems[id]
+	registry.Mutex.RUnlock()
+	return p, ok
+}
+
+// List the registered problems ordered by their id
+func All() []*Problem {
+	registry.Mutex.RLock()
+	all := make([]*Problem, 0, len(registry.problems))
+	for _, p := range registry.problems {
+		all = append(all, p)
+	}
+	registry.Mutex.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return lessId(all[i].Id, all[j].Id)
+	})
+	return all
+}
+
+// Numeric ids are ordered by value, others alphabetically after them
+func lessId(a, b string) bool {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+	switch {
+	case errX == nil && errY == nil:
+		return x < y
+	case errX == nil:
+		return true
+	case errY == nil:
+		return false
+	default:
+		return a < b
+	}
+}
+
+// Public description of the problem sent to the clients
+func (p *Problem) Info() mod.ProblemInfo {
+	examples := make([]mod.ProblemExample, len(p.Examples))
+	for i, e := range p.Examples {
+		examples[i] = mod.ProblemExample{Input: e.Input, Output: e.Output}
+	}
+
+	return mod.ProblemInfo{
+		Id:          p.Id,
+		Name:        p.Name,
+		Description: p.Description,
+		Input:       p.Schema.String(),
+		Examples:    examples,
+	}
+}
+
+// Longest time a solve of the problem may take
+func (p *Problem) MaxDuration() time.Duration {
+	if p.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return p.Timeout
+}
+
+// Check the input against the schema of the problem
+func (p *Problem) Validate(arr []interface{}) error {
+	return p.Schema.Validate(arr)
+}
+
+// Describe the expected input in words
+func (s *Schema) String() string {
+	var desc string
+	switch s.Type {
+	case STRING:
+		desc = "array of strings"
+	case INT:
+		desc = "array of integers"
+	case NATURAL:
+		desc = "array of natural numbers"
+	default:
+		desc = "array"
+	}
+
+	if s.SameLength {
+		desc += " of the same length"
+	}
+
+	switch {
+	case s.MinLen > 0 && s.MaxLen > 0:
+		desc += fmt.Sprintf(", with %v to %v elements", s.MinLen, s.MaxLen)
+	case s.MinLen > 0:
+		desc += fmt.Sprintf(", with at least %v elements", s.MinLen)
+	case s.MaxLen > 0:
+		desc += fmt.Sprintf(", with at most %v elements", s.MaxLen)
+	}
+
+	return desc
+}
+
+func (s *Schema) Validate(arr []interface{}) error {
+	if len(arr) < s.MinLen {
+		return fmt.Errorf("array length should be geq %v", s.MinLen)
+	}
+	if s.MaxLen > 0 && len(arr) > s.MaxLen {
+		return fmt.Errorf("array length should be leq %v", s.MaxLen)
+	}
+
+	switch s.Type {
+	case STRING:
+		if !IsArrayOfType(arr, "") {
+			return ArrTypeMismatchError{Type: STRING}
+		}
+		if s.SameLength && !HaveSameLength(arr) {
+			return fmt.Errorf("strings are not equal")
+		}
+	case INT:
+		if !IsArrayOfInts(arr) {
+			return ArrTypeMismatchError{Type: INT}
+		}
+	case NATURAL:
+		if !IsArrayOfInts(arr) {
+			return ArrTypeMismatchError{Type: INT}
+		}
+		for _, e := range arr {
+			if e.(float64) < 0 {
+				return fmt.Errorf("cannot use negative numbers")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Check if all elements are json numbers without a fractional part
+func IsArrayOfInts(arr []interface{}) bool {
+	for _, e := range arr {
+		if reflect.TypeOf(e) != reflect.TypeOf(0.) ||
+			float64(int(e.(float64))) != e.(float64) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if all elements are strings of the same length
+func HaveSameLength(arr []interface{}) bool {
+	for _, e := range arr {
+		if len(e.(string)) != len(arr[0].(string)) {
+			return false
+		}
+	}
+	return true
+}
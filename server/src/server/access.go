@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	mod "aio/common/src/model"
+)
+
+// Check that the role of the client allows the command, returns the
+// rejection response otherwise. Malformed arguments are left for the
+// resolvers to report.
+func (s *Server) Permit(client string, com mod.Command) *mod.ResponseModel {
+	policy := s.Settings.Policy
+	if policy == nil {
+		return nil
+	}
+
+	role := policy.RoleOf(client)
+	if !role.CanUse(com.Verb) {
+		return Forbidden("not allowed to use %v", com.Verb)
+	}
+
+	switch com.Verb {
+	case mod.LIST:
+		list, e := DecodeListCommand(com)
+		if e == nil && !role.CanList(list.Entity) {
+			return Forbidden("not allowed to list %v", list.Entity)
+		}
+	case mod.SOLVE, mod.SUBMIT:
+		solve, e := DecodeSolveCommand(com)
+		if e != nil {
+			return nil
+		}
+		for _, problem := range problemsOf(solve) {
+			if !role.CanSolve(problem) {
+				return Forbidden("not allowed to solve problem %v", problem)
+			}
+		}
+	}
+	return nil
+}
+
+func Forbidden(format string, v ...interface{}) *mod.ResponseModel {
+	return &mod.ResponseModel{
+		Content: fmt.Sprintf(format, v...),
+		Status: mod.FORBIDDEN,
+	}
+}
+
+// Problems solved by the command, one per item of a batch
+func problemsOf(solve mod.SolveCommand) []string {
+	if len(solve.Batch) == 0 {
+		return []string{solve.Problem}
+	}
+
+	problems := make([]string, len(solve.Batch))
+	for i, item := range solve.Batch {
+		problems[i] = item.Problem
+		if problems[i] == "" {
+			problems[i] = solve.Problem
+		}
+	}
+	return problems
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	mod "aio/common/src/model"
+)
+
+// Solve the items of a batch in parallel, each one succeeding or failing
+// on its own, and bundle their results in the order of the items
+func (s *Server) SolveBatch(
+	ctx context.Context,
+	solve mod.SolveCommand,
+	progress Progress,
+) *mod.ResponseModel {
+	if s.Settings.MaxBatchLen > 0 && len(solve.Batch) > s.Settings.MaxBatchLen {
+		return &mod.ResponseModel{
+			Content: fmt.Sprintf("batch length should be leq %v", s.Settings.MaxBatchLen),
+			Status:  mod.BADREQUEST,
+		}
+	}
+
+	items := make([]mod.BatchItemResult, len(solve.Batch))
+
+	var wg sync.WaitGroup
+	for i, item := range solve.Batch {
+		problem := item.Problem
+		if problem == "" {
+			problem = solve.Problem
+		}
+
+		wg.Add(1)
+		go func(i int, problem string, arr []interface{}) {
+			defer wg.Done()
+			items[i] = s.solveBatchItem(ctx, problem, arr, progress.Item(i))
+		}(i, problem, item.Array)
+	}
+	wg.Wait()
+
+	return &mod.ResponseModel{
+		Content: mod.BatchResult{Items: items},
+		Status:  mod.OK,
+	}
+}
+
+func (s *Server) solveBatchItem(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+	progress Progress,
+) (item mod.BatchItemResult) {
+	item.Problem = problem
+
+	// A failing item must not take down the whole batch
+	defer func() {
+		if r := recover(); r != nil {
+			s.Logger.Log(fmt.Sprintf("recovered while solving %v %v: %v\n", problem, arr, r))
+			item.Status = mod.ERROR
+			item.Result = nil
+			item.Error = "internal server error"
+		}
+	}()
+
+	// The items of an accepted batch wait for room in the queue
+	res := s.solveItem(ctx, problem, arr, progress, true)
+	item.Status = res.Status
+
+	if sol, ok := res.Content.(mod.SolveResult); ok {
+		item.Result = sol.Result
+	} else {
+		item.Error = fmt.Sprint(res.Content)
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	mod "aio/common/src/model"
+)
+
+// A request in progress on a session, which its client may still cancel
+type running struct {
+	cancel context.CancelFunc
+}
+
+// Context of a request, done once the client cancels the request or the
+// timeout it asked for expires. The returned function must be called
+// when the request is answered.
+func (s *Server) RequestContext(
+	session *Session,
+	req *mod.RequestModel,
+) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if req.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(
+			context.Background(),
+			time.Millisecond * req.Timeout,
+		)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	forget := session.Start(req.Id, cancel)
+	return ctx, func() {
+		forget()
+		cancel()
+	}
+}
+
+// Cancel the request named by a cancel request
+func (s *Server) ResolveCancel(
+	session *Session,
+	req *mod.RequestModel,
+	res *mod.ResponseModel,
+) (err error) {
+	cancel, e := DecodeCancel(req)
+	if e != nil {
+		*res = *BadRequest(e)
+		return
+	}
+
+	if !session.Cancel(cancel.RequestId) {
+		*res = mod.ResponseModel{
+			Content: fmt.Sprintf("no request %v in progress", cancel.RequestId),
+			Status: mod.BADREQUEST,
+		}
+		return
+	}
+
+	*res = mod.ResponseModel{
+		Content: fmt.Sprintf("request %v cancelled", cancel.RequestId),
+		Status: mod.OK,
+	}
+	return
+}
+
+// Check if the work stopped because its context is done
+func IsInterrupted(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// Response of a request which stopped because its context is done
+func Interrupted(err error) *mod.ResponseModel {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &mod.ResponseModel{
+			Content: "request timed out",
+			Status: mod.TIMEOUT,
+		}
+	}
+	return &mod.ResponseModel{
+		Content: "request cancelled",
+		Status: mod.CANCELLED,
+	}
+}
+
+// Remember a request in progress until the returned function is called.
+// Requests without an id cannot be cancelled.
+func (ss *Session) Start(id string, cancel context.CancelFunc) (forget func()) {
+	if id == "" {
+		return func() {}
+	}
+
+	r := &running{cancel: cancel}
+	ss.Mutex.Lock()
+	if ss.running == nil {
+		ss.running = make(map[string][]*running)
+	}
+	ss.running[id] = append(ss.running[id], r)
+	ss.Mutex.Unlock()
+
+	return func() {
+		ss.Mutex.Lock()
+		defer ss.Mutex.Unlock()
+
+		same := ss.running[id]
+		for i := range same {
+			if same[i] == r {
+				same = append(same[:i], same[i+1:]...)
+				break
+			}
+		}
+		if len(same) == 0 {
+			delete(ss.running, id)
+		} else {
+			ss.running[id] = same
+		}
+	}
+}
+
+// Cancel the requests in progress with the given id, reporting if any
+func (ss *Session) Cancel(id string) bool {
+	ss.Mutex.Lock()
+	same := append([]*running(nil), ss.running[id]...)
+	ss.Mutex.Unlock()
+
+	for _, r := range same {
+		r.cancel()
+	}
+	return len(same) != 0
+}
+
+// Cancel every request in progress, their responses cannot be delivered
+func (ss *Session) CancelAll() {
+	ss.Mutex.Lock()
+	all := ss.running
+	ss.running = nil
+	ss.Mutex.Unlock()
+
+	for _, same := range all {
+		for _, r := range same {
+			r.cancel()
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"aio/common/src/codec"
+	mod "aio/common/src/model"
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Raised when the content of a request does not have the expected shape
+type BadRequestError struct {
+	Reason string
+}
+
+func (e BadRequestError) Error() string {
+	return e.Reason
+}
+
+func badRequest(format string, v ...interface{}) error {
+	return BadRequestError{Reason: fmt.Sprintf(format, v...)}
+}
+
+// Decode the generic json content of a request into a typed structure,
+// rejecting unknown fields and mismatched types
+func decodeStrict(content interface{}, v interface{}, what string) error {
+	if content == nil {
+		return badRequest("missing %s", what)
+	}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return badRequest("invalid %s: %v", what, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(v); err != nil {
+		return badRequest("invalid %s: %v", what, err)
+	}
+
+	return nil
+}
+
+// Decode the salute content, which is empty unless the client asks for
+// a different framing
+func DecodeSalute(req *mod.RequestModel) (salute mod.SaluteContent, err error) {
+	if text, ok := req.Content.(string); req.Content == nil || ok && text == "" {
+		return
+	}
+	if err = decodeStrict(req.Content, &salute, "salute"); err != nil {
+		return
+	}
+	if salute.Framing != "" && !codec.IsFraming(salute.Framing) {
+		err = badRequest("unsupported framing %v", salute.Framing)
+	}
+	return
+}
+
+func DecodeCommand(req *mod.RequestModel) (com mod.Command, err error) {
+	if err = decodeStrict(req.Content, &com, "command"); err != nil {
+		return
+	}
+	if com.Verb == "" {
+		err = badRequest("missing command verb")
+	}
+	return
+}
+
+func DecodeSolveCommand(com mod.Command) (solve mod.SolveCommand, err error) {
+	if err = decodeStrict(com.Args, &solve, "solve arguments"); err != nil {
+		return
+	}
+
+	// Batches carry the arrays in their items
+	if len(solve.Batch) != 0 {
+		if solve.Array != nil {
+			return solve, badRequest("array and batch cannot be used together")
+		}
+		for i, item := range solve.Batch {
+			if item.Problem == "" && solve.Problem == "" {
+				return solve, badRequest("missing problem of batch item %v", i)
+			} else if item.Array == nil {
+				return solve, badRequest("missing array of batch item %v", i)
+			}
+		}
+		return
+	}
+
+	if solve.Problem == "" {
+		err = badRequest("missing problem")
+	} else if solve.Array == nil {
+		err = badRequest("missing array")
+	}
+	return
+}
+
+func DecodeListCommand(com mod.Command) (list mod.ListCommand, err error) {
+	if err = decodeStrict(com.Args, &list, "list arguments"); err != nil {
+		return
+	}
+	if list.Entity == "" {
+		err = badRequest("missing list entity")
+	}
+	return
+}
+
+func DecodeDescribeCommand(com mod.Command) (describe mod.DescribeCommand, err error) {
+	if err = decodeStrict(com.Args, &describe, "describe arguments"); err != nil {
+		return
+	}
+	if describe.Problem == "" {
+		err = badRequest("missing problem")
+	}
+	return
+}
+
+func DecodeJobCommand(com mod.Command) (job mod.JobCommand, err error) {
+	if err = decodeStrict(com.Args, &job, "job arguments"); err != nil {
+		return
+	}
+	if job.Job == "" {
+		err = badRequest("missing job")
+	}
+	return
+}
+
+func DecodeCancel(req *mod.RequestModel) (cancel mod.CancelContent, err error) {
+	if err = decodeStrict(req.Content, &cancel, "cancel"); err != nil {
+		return
+	}
+	if cancel.RequestId == "" {
+		err = badRequest("missing request id")
+	}
+	return
+}
+
+// Response sent back for a request which could not be decoded
+func BadRequest(err error) *mod.ResponseModel {
+	return &mod.ResponseModel{
+		Content: err.Error(),
+		Status:  mod.BADREQUEST,
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"aio/common/src/host"
+	mod "aio/common/src/model"
+)
+
+// Check the name against the name policy, the name of the server being
+// reserved as well. Returns the rejection response for invalid names.
+func (s *Server) CheckName(name string) *mod.ResponseModel {
+	if err := s.Settings.Names.Check(name, s.Name()); err != nil {
+		return &mod.ResponseModel{
+			Content: err.Error(),
+			Status: mod.BADNAME,
+		}
+	}
+	return nil
+}
+
+// Name proven by the certificate the client presented, if any
+func (ss *Session) Identity() (string, bool) {
+	return host.PeerIdentity(ss.Conn)
+}
+
+// Clients authenticated with a certificate can only register under the
+// common name of its subject, returns the rejection response otherwise
+func (s *Server) CheckIdentity(session *Session, name string) *mod.ResponseModel {
+	certified, ok := session.Identity()
+	if !ok {
+		return nil
+	}
+
+	if certified == "" {
+		return &mod.ResponseModel{
+			Content: "the certificate does not name a client",
+			Status: mod.BADNAME,
+		}
+	}
+	if name != certified {
+		return &mod.ResponseModel{
+			Content: fmt.Sprintf(
+				"name %s does not match the certificate issued to %s",
+				name,
+				certified,
+			),
+			Status: mod.BADNAME,
+		}
+	}
+	return nil
+}
+
+// Check the credentials of the salute against the user file, returns the
+// rejection response when they do not match
+func (s *Server) Authenticate(
+	session *Session,
+	name string,
+	salute mod.SaluteContent,
+) *mod.ResponseModel {
+	if s.Users == nil {
+		return nil
+	}
+
+	// A verified certificate already proves the name
+	if _, ok := session.Identity(); ok {
+		return nil
+	}
+
+	// Take the token of a failure before hashing anything, so the salutes
+	// sent at once by an address cannot all get past the limit
+	host := session.RemoteHost()
+	if ok, wait := s.throttle.logins.Take(host); !ok {
+		return RateLimited(wait, "too many failed salutes from your address")
+	}
+
+	if !s.Users.Authenticate(name, salute.Password, salute.Token) {
+		return &mod.ResponseModel{
+			Content: "invalid credentials",
+			Status: mod.UNAUTHORIZED,
+		}
+	}
+
+	// Only the failures count against the limit
+	s.throttle.logins.Put(host)
+	return nil
+}
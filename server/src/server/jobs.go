@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"aio/server/src/jobs"
+	mod "aio/common/src/model"
+)
+
+// Start solving the command in the background, answering right away
+// with the job which will hold the response
+func (s *Server) ResolveSubmitCommand(
+	com mod.Command,
+	res *mod.ResponseModel,
+	owner string,
+) (err error) {
+	solve, e := DecodeSolveCommand(com)
+	if e != nil {
+		*res = *BadRequest(e)
+		return
+	}
+
+	problem := solve.Problem
+	if len(solve.Batch) != 0 {
+		problem = "batch"
+	}
+
+	// The server waits for the jobs when shutting down
+	done, ok := s.Track()
+	if !ok {
+		*res = *s.GoingAway()
+		return
+	}
+
+	job, e := s.Jobs.Add(owner, problem)
+	if e != nil {
+		done()
+		*res = mod.ResponseModel{
+			Content: fmt.Sprintf("maximum of %v jobs reached", s.Settings.MaxJobs),
+			Status: mod.BUSY,
+		}
+		return
+	}
+
+	go s.runJob(job, solve, done)
+
+	*res = mod.ResponseModel{
+		Content: s.Jobs.Info(job),
+		Status: mod.OK,
+	}
+	return
+}
+
+// Solve the command of the job, which keeps running after its client
+// disconnects
+func (s *Server) runJob(job *jobs.Job, solve mod.SolveCommand, done func()) {
+	defer done()
+
+	res := &mod.ResponseModel{
+		Content: "internal server error",
+		Status: mod.ERROR,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.Logger.Log(fmt.Sprintf("recovered while running job %v: %v\n", job.Id, r))
+		}
+		s.Jobs.Finish(job, res)
+	}()
+
+	progress := func(content string) {
+		s.Jobs.Progress(job, content)
+	}
+
+	// The job was accepted, it waits for a worker rather than failing
+	// when the queue is full
+	if len(solve.Batch) != 0 {
+		res = s.SolveBatch(context.Background(), solve, progress)
+	} else {
+		res = s.solveItem(context.Background(), solve.Problem, solve.Array, progress, true)
+	}
+}
+
+// Answer the status and result verbs about a job of the client
+func (s *Server) ResolveJobCommand(
+	com mod.Command,
+	res *mod.ResponseModel,
+	owner string,
+) (err error) {
+	args, e := DecodeJobCommand(com)
+	if e != nil {
+		*res = *BadRequest(e)
+		return
+	}
+
+	job, ok := s.Jobs.Get(owner, args.Job)
+	if !ok {
+		*res = mod.ResponseModel{
+			Content: fmt.Sprintf("unknown job %v", args.Job),
+			Status: mod.BADREQUEST,
+		}
+		return
+	}
+
+	if com.Verb == mod.STATUS {
+		*res = mod.ResponseModel{
+			Content: s.Jobs.Info(job),
+			Status: mod.OK,
+		}
+		return
+	}
+
+	if result := s.Jobs.Result(job); result != nil {
+		*res = *result
+	} else {
+		*res = mod.ResponseModel{
+			Content: fmt.Sprintf("job %v is still pending: %v", job.Id, s.Jobs.Info(job).Progress),
+			Status: mod.PENDING,
+		}
+	}
+	return
+}
+
+// List the jobs of the client
+func (s *Server) ListJobs(owner string) mod.JobList {
+	list := mod.JobList{Jobs: make([]mod.JobInfo, 0)}
+	for _, job := range s.Jobs.List(owner) {
+		list.Jobs = append(list.Jobs, s.Jobs.Info(job))
+	}
+	return list
+}
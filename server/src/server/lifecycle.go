@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrIdleTimeout       = errors.New("connection idle timeout")
+	ErrConnectionDropped = errors.New("connection dropped")
+)
+
+// Called after a client name was removed from the registered clients.
+// The reason is nil when the client left with a bye request.
+type DisconnectHook func(name string, reason error)
+
+type hooks struct {
+	disconnect []DisconnectHook
+	Mutex      sync.Mutex
+}
+
+// Subscribe to client deregistrations
+func (s *Server) OnDisconnect(hook DisconnectHook) {
+	s.hooks.Mutex.Lock()
+	s.hooks.disconnect = append(s.hooks.disconnect, hook)
+	s.hooks.Mutex.Unlock()
+}
+
+// Remove the client owning the session from the registered clients
+// and notify the subscribers
+func (s *Server) Deregister(session *Session, reason error) {
+	name := session.Unbind()
+	if name == "" {
+		return
+	}
+	s.Clients.Delete(name)
+
+	// Log client disconnection
+	if reason == nil {
+		s.Logger.Log("client " + name + " has disconnected\n")
+	} else {
+		s.Logger.Log("client " + name + " was deregistered: ", reason, "\n")
+	}
+
+	// Copy the hooks so they can subscribe others while running
+	s.hooks.Mutex.Lock()
+	subscribers := make([]DisconnectHook, len(s.hooks.disconnect))
+	copy(subscribers, s.hooks.disconnect)
+	s.hooks.Mutex.Unlock()
+
+	for _, hook := range subscribers {
+		hook(name, reason)
+	}
+}
+
+// Extend the read deadline of the connection by the idle timeout. A
+// connection waiting for the responses of its requests is not idle, it
+// gets no deadline until they are answered.
+func (s *Server) KeepAlive(session *Session) error {
+	if s.Settings.IdleTimeout <= 0 {
+		return nil
+	}
+
+	// Decide under the lock, a request may start or end meanwhile
+	session.Mutex.Lock()
+	defer session.Mutex.Unlock()
+
+	var deadline time.Time
+	if session.busy == 0 {
+		deadline = time.Now().Add(time.Millisecond * s.Settings.IdleTimeout)
+	}
+	return session.Conn.SetReadDeadline(deadline)
+}
+
+// Translate a read error into the reason the connection ended
+func (s *Server) DropReason(err error) error {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf(
+			"%w: no request for %v ms",
+			ErrIdleTimeout,
+			int(s.Settings.IdleTimeout),
+		)
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: closed by client", ErrConnectionDropped)
+	default:
+		return fmt.Errorf("%w: %v", ErrConnectionDropped, err)
+	}
+}
+
+// Deregister the owner of the session and close its connection
+func (s *Server) Release(session *Session, reason error) {
+	s.forget(session)
+	session.CancelAll()
+	s.Deregister(session, reason)
+	if err := session.Conn.Close(); err != nil && reason == nil {
+		s.Logger.Log("failed to close connection: ", err, "\n")
+	}
+}
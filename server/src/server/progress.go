@@ -0,0 +1,29 @@
+package server
+
+import (
+	"fmt"
+	mod "aio/common/src/model"
+)
+
+// Reports the progress of a request to its client
+type Progress func(content string)
+
+// Send the progress of the request as LOG messages
+func (s *Server) Progress(session *Session, req *mod.RequestModel) Progress {
+	return func(content string) {
+		if err := s.Send(session, &mod.ResponseModel{
+			Id: req.Id,
+			Content: content,
+			Status: mod.LOG,
+		}); err != nil {
+			s.Logger.Log("failed to report progress: ", err, "\n")
+		}
+	}
+}
+
+// Prefix the progress of a batch item with its index
+func (p Progress) Item(i int) Progress {
+	return func(content string) {
+		p(fmt.Sprintf("item %v: %s", i, content))
+	}
+}
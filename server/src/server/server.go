@@ -3,8 +3,14 @@ package server
 import (
 	"net"
 	"fmt"
+	"context"
 	"strings"
-	"encoding/json"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"aio/server/src/jobs"
+	"aio/server/src/users"
+	"aio/server/src/pool"
 	"aio/server/src/pmap"
 	"aio/server/src/settings"
 	mod "aio/common/src/model"
@@ -17,7 +23,13 @@ type Server struct {
 	Logger 	 		*logg.Logger
 	Listener		*net.Listener
 	Settings 		*settings.ServerSettings
-	ProblemMapper	map[string]func([]interface{}) (string, error)
+	Pool			*pool.Pool
+	Jobs			*jobs.Store
+	Users			*users.Store
+	hooks			hooks
+	throttle		*throttle
+	tracker			tracker
+	connections		int32
 }
 
 func (s *Server) Init(configFilePath string) (err error) {
@@ -26,20 +38,49 @@ func (s *Server) Init(configFilePath string) (err error) {
 		return
 	}
 
-	// Map the requests to the solution functions
-	s.ProblemMapper = map[string]func([]interface{}) (string, error) {
-		"1": prob.Problem1,
-		"2": prob.Problem2,
-		"3": prob.Problem3,
-		"8": prob.Problem8,
-	}
-
 	// Construct and initialize objs
 	s.Clients = new(pmap.PMap)
 	s.Clients.Map = make(map[string]bool)
 	s.Listener = new(net.Listener)
 	s.Logger = new(logg.Logger)
 	s.Logger.Entity = s
+
+	// Anyone may claim any name without a user file
+	if s.Settings.Users != "" {
+		if s.Users, err = users.Load(s.Settings.Users); err != nil {
+			return
+		}
+	}
+
+	// Share the solvers between all the clients
+	workers := s.Settings.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	depth := s.Settings.QueueDepth
+	if depth <= 0 {
+		depth = 4 * workers
+	}
+
+	// A batch larger than the queue would keep it full on its own, and
+	// every other solve would be answered busy meanwhile
+	if s.Settings.MaxBatchLen > depth {
+		return fmt.Errorf(
+			"maxBatchLen %v should be leq the queue depth %v",
+			s.Settings.MaxBatchLen,
+			depth,
+		)
+	}
+	s.Pool = pool.New(workers, depth)
+
+	// Limit the problems each client may solve
+	s.throttle = newThrottle(s.Settings.Limits)
+
+	// Keep the finished jobs for the clients coming back later
+	s.Jobs = jobs.New(
+		time.Millisecond * s.Settings.JobRetention,
+		s.Settings.MaxJobs,
+	)
 	return
 }
 
@@ -48,10 +89,7 @@ func (s *Server) Start(
 	errorHandler func(*Server, error),
 ) (err error) {
 	// Start listening to requests
-	*s.Listener, err = net.Listen(
-		s.Settings.Host.Protocol,
-		s.Settings.Host.Server(),
-	)
+	*s.Listener, err = s.Settings.Host.Listen()
 
 	// Assure no error occurred
 	if err != nil {
@@ -80,84 +118,190 @@ func (s *Server) Start(
 		var c net.Conn
 		c, err = (*s.Listener).Accept()
 
-		if err != nil {
+		if err != nil && s.IsClosing() {
+			return ErrServerClosed
+		} else if err != nil {
 			return
 		}
 
-		go callback(s, c, e)
+		// Refuse the connection right away when there is no room left
+		if s.Settings.RejectOnAccept && s.IsFull() {
+			s.Logger.Log("rejected connection from ", c.RemoteAddr(), ": server full\n")
+			go s.Reject(c)
+			continue
+		}
+
+		atomic.AddInt32(&s.connections, 1)
+		go func(c net.Conn) {
+			defer atomic.AddInt32(&s.connections, -1)
+
+			// Contain the failures of a connection to its goroutine
+			defer func() {
+				if r := recover(); r != nil {
+					c.Close()
+					e <- fmt.Errorf("connection %v crashed: %v", c.RemoteAddr(), r)
+				}
+			}()
+
+			callback(s, c, e)
+		}(c)
 	}
 }
 
-func (s *Server) Parse(request string, req *mod.RequestModel) (err error) {
-	if err = json.Unmarshal([]byte(request), req); err != nil {
-		return err
+// Check if the server reached the maximum number of clients,
+// counting both registered clients and open connections
+func (s *Server) IsFull() bool {
+	if s.Settings.MaxClients <= 0 {
+		return false
 	}
-	return nil
+	return s.Clients.Size() >= s.Settings.MaxClients ||
+		int(atomic.LoadInt32(&s.connections)) >= s.Settings.MaxClients
 }
 
-func (s *Server) Solve(problem string, arr []interface{}) (string, error) {
-	if solver, ok := s.ProblemMapper[problem]; !ok {
-		return "", fmt.Errorf("cannot handle request %v", problem)
-	} else {
-		return solver(arr)
+func (s *Server) ServerFull() *mod.ResponseModel {
+	return &mod.ResponseModel{
+		Content: fmt.Sprintf(
+			"maximum of %v clients reached",
+			s.Settings.MaxClients,
+		),
+		Status: mod.SERVERFULL,
 	}
 }
 
-func (s *Server) Send(conn net.Conn, response *mod.ResponseModel) (err error) {
-	if response == nil {
-		return
+// Inform the client that there is no room left and close the connection
+func (s *Server) Reject(conn net.Conn) {
+	defer conn.Close()
+	if err := s.Send(NewSession(conn, s.Settings.MaxFrameSize), s.ServerFull()); err != nil {
+		s.Logger.Log("failed to reject connection: ", err, "\n")
+	}
+}
+
+// Read the next request sent on the connection of the session
+func (s *Server) Receive(session *Session) (req *mod.RequestModel, err error) {
+	req = new(mod.RequestModel)
+	if err = session.Codec.ReadMessage(req); err != nil {
+		return nil, err
 	}
+	return req, nil
+}
 
-	// Transform response to json
-	var raw []byte
-	if raw, err = json.Marshal(*response); err != nil {
-		return err
+func (s *Server) Solve(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+) (interface{}, error) {
+	p, ok := prob.Lookup(problem)
+	if !ok {
+		return nil, fmt.Errorf("cannot handle request %v", problem)
+	}
+
+	// Give up once the problem runs for longer than it should
+	ctx, cancel := context.WithTimeout(ctx, p.MaxDuration())
+	defer cancel()
+	return p.Solve(ctx, arr)
+}
+
+func (s *Server) Send(session *Session, response *mod.ResponseModel) (err error) {
+	if response == nil {
+		return
 	}
 
 	// Send the message to the client
-	fmt.Fprintf(conn, "%v\n", string(raw))
-	return nil
+	return session.Codec.WriteMessage(*response)
 }
 
 func (s *Server) ProcessRequest(
+	ctx context.Context,
+	session *Session,
 	req *mod.RequestModel,
 ) (res *mod.ResponseModel, err error) {
 	// Log client request
 	s.Logger.Log(
-		fmt.Sprintf("client %s made a request: %v\n", req.Sender, *req),
+		fmt.Sprintf("client %s made a request: %v\n", req.Sender, req.Redacted()),
 	)
 
 	// Create a response
 	res = new(mod.ResponseModel)
 
-	// Compute the response
-	switch req.Type {
-	case mod.COMMAND:
-		// Validate the client
-		if !s.Clients.Exists(req.Sender) {
-			*res = mod.ResponseModel{
-				Content: "client not registered",
-				Status: mod.UNREGISTERED,
+	// Echo the request identifier so the client can correlate the response
+	defer func() {
+		if res != nil {
+			res.Id = req.Id
+		}
+	}()
+
+	// A bad request must never take down the connection
+	defer func() {
+		if r := recover(); r != nil {
+			s.Logger.Log(
+				fmt.Sprintf("recovered while processing %v: %v\n", req.Redacted(), r),
+			)
+			res = &mod.ResponseModel{
+				Content: "internal server error",
+				Status: mod.ERROR,
 			}
+		}
+	}()
+
+	// Only the owner of the connection may issue requests on it
+	if req.Type != mod.SALUTE {
+		if reject := s.Authorize(session, req); reject != nil {
+			*res = *reject
 			// Log server response
 			s.Logger.Log(
 				fmt.Sprintf("send response %v to client %s\n", *res, req.Sender),
 			)
 			return
 		}
+	}
+
+	// Compute the response
+	switch req.Type {
+	case mod.COMMAND:
+		// Skip the commands cancelled or timed out before they started
+		if e := ctx.Err(); e != nil {
+			*res = *Interrupted(e)
+			break
+		}
 
 		// Extract the command
-		com := mod.Command{
-			Verb: req.Content.(map[string]interface{})["verb"].(string),
-			Args: req.Content.(map[string]interface{})["args"],
+		com, e := DecodeCommand(req)
+		if e != nil {
+			*res = *BadRequest(e)
+			break
+		}
+
+		// Check the role of the client before doing anything
+		if reject := s.Permit(req.Sender, com); reject != nil {
+			*res = *reject
+			break
 		}
 
+		// Slow down the clients solving too many problems
+		reject, refund := s.Throttle(session, req.Sender, com)
+		if reject != nil {
+			*res = *reject
+			break
+		}
+		// The commands answered busy were not served
+		defer func() {
+			if res != nil && res.Status == mod.BUSY {
+				refund()
+			}
+		}()
+
 		// Solve each specific verb
 		switch com.Verb {
 		case mod.SOLVE:
-			err = s.ResolveSolveCommand(com, res)
+			err = s.ResolveSolveCommand(ctx, com, res, s.Progress(session, req))
 		case mod.LIST:
-			err = s.ResolveListCommand(com, res)
+			err = s.ResolveListCommand(com, res, req.Sender)
+		case mod.DESCRIBE:
+			err = s.ResolveDescribeCommand(com, res)
+		case mod.SUBMIT:
+			err = s.ResolveSubmitCommand(com, res, req.Sender)
+		case mod.STATUS, mod.RESULT:
+			err = s.ResolveJobCommand(com, res, req.Sender)
 		default:
 			*res = mod.ResponseModel{
 				Content: "invalid verb",
@@ -165,7 +309,51 @@ func (s *Server) ProcessRequest(
 			}
 		}
 	case mod.SALUTE:
-		if s.Clients.Exists(req.Sender) {
+		if owner := session.Owner(); owner != "" {
+			*res = mod.ResponseModel{
+				Content: fmt.Sprintf("connection already registered as %s", owner),
+				Status: mod.ALRDREGISTERED,
+			}
+			// Log server response
+			s.Logger.Log(
+				fmt.Sprintf("send response %v to client %s\n", *res, req.Sender),
+			)
+			return
+		}
+		salute, e := DecodeSalute(req)
+		if e != nil {
+			*res = *BadRequest(e)
+			break
+		}
+
+		// The name must follow the name policy and be the one proven
+		// by the client certificate or by the credentials of the salute
+		reject := s.CheckName(req.Sender)
+		if reject == nil {
+			reject = s.CheckIdentity(session, req.Sender)
+		}
+		if reject == nil {
+			reject = s.Authenticate(session, req.Sender, salute)
+		}
+		if reject != nil {
+			*res = *reject
+			// Log server response
+			s.Logger.Log(
+				fmt.Sprintf("send response %v to client %s\n", *res, req.Sender),
+			)
+			return
+		}
+
+		added, full := s.Clients.TryAddLimited(req.Sender, s.Settings.MaxClients)
+		if full {
+			*res = *s.ServerFull()
+			// Log server response
+			s.Logger.Log(
+				fmt.Sprintf("send response %v to client %s\n", *res, req.Sender),
+			)
+			return
+		}
+		if !added {
 			*res = mod.ResponseModel{
 				Content: "client already registered",
 				Status: mod.ALRDREGISTERED,
@@ -176,7 +364,8 @@ func (s *Server) ProcessRequest(
 			)
 			return
 		}
-		s.Clients.Add(req.Sender)
+		session.Bind(req.Sender)
+		session.NegotiateFraming(salute.Framing)
 
 		// Log client connection
 		defer s.Logger.Log("client " + req.Sender + " has connected\n")
@@ -185,11 +374,11 @@ func (s *Server) ProcessRequest(
 			Content: "registration successful",
 			Status: mod.OK,
 		}
+	case mod.CANCEL:
+		err = s.ResolveCancel(session, req, res)
 	case mod.BYE:
-		s.Clients.Delete(req.Sender)
-
-		// Log client connection
-		defer s.Logger.Log("client " + req.Sender + " has disconnected\n")
+		// Log client disconnection
+		defer s.Deregister(session, nil)
 
 		*res = mod.ResponseModel{
 			Content: "connection stopped",
@@ -219,16 +408,70 @@ func (s *Server) ProcessRequest(
 	return
 }
 
-func (s *Server) ResolveListCommand(com mod.Command, res *mod.ResponseModel) (err error) {
-	entity := com.Args.(map[string]interface{})["entity"].(string)
+// Check that the request was issued by the client owning the session,
+// returning the rejection response otherwise
+func (s *Server) Authorize(
+	session *Session,
+	req *mod.RequestModel,
+) *mod.ResponseModel {
+	if !session.IsRegistered() {
+		return &mod.ResponseModel{
+			Content: "client not registered",
+			Status: mod.UNREGISTERED,
+		}
+	}
+
+	if !session.Owns(req.Sender) {
+		return &mod.ResponseModel{
+			Content: fmt.Sprintf(
+				"connection is registered as %s, not %s",
+				session.Owner(),
+				req.Sender,
+			),
+			Status: mod.BADSENDER,
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) ResolveListCommand(
+	com mod.Command,
+	res *mod.ResponseModel,
+	owner string,
+) (err error) {
+	list, e := DecodeListCommand(com)
+	if e != nil {
+		*res = *BadRequest(e)
+		return
+	}
 
-	switch entity {
+	switch list.Entity {
 	case "clients":
 		clients := strings.Join(s.Clients.Keys(), ",")
 		*res = mod.ResponseModel{
 			Content: fmt.Sprintf("registered clients: %v", clients),
 			Status: mod.OK,
 		}
+	case "problems":
+		catalog := mod.ProblemCatalog{Problems: make([]mod.ProblemInfo, 0)}
+		for _, p := range prob.All() {
+			info := p.Info()
+			// Keep the listing short, one example per problem
+			if len(info.Examples) > 1 {
+				info.Examples = info.Examples[:1]
+			}
+			catalog.Problems = append(catalog.Problems, info)
+		}
+		*res = mod.ResponseModel{
+			Content: catalog,
+			Status: mod.OK,
+		}
+	case "jobs":
+		*res = mod.ResponseModel{
+			Content: s.ListJobs(owner),
+			Status: mod.OK,
+		}
 	default:
 		*res = mod.ResponseModel{
 			Content: "invalid list entity",
@@ -239,41 +482,194 @@ func (s *Server) ResolveListCommand(com mod.Command, res *mod.ResponseModel) (er
 	return
 }
 
-func (s *Server) ResolveSolveCommand(com mod.Command, res *mod.ResponseModel) (err error) {
+func (s *Server) ResolveDescribeCommand(com mod.Command, res *mod.ResponseModel) (err error) {
+	describe, e := DecodeDescribeCommand(com)
+	if e != nil {
+		*res = *BadRequest(e)
+		return
+	}
+
+	if p, ok := prob.Lookup(describe.Problem); !ok {
+		*res = mod.ResponseModel{
+			Content: fmt.Sprintf("unknown problem %v", describe.Problem),
+			Status: mod.BADREQUEST,
+		}
+	} else {
+		*res = mod.ResponseModel{
+			Content: p.Info(),
+			Status: mod.OK,
+		}
+	}
+
+	return
+}
+
+func (s *Server) ResolveSolveCommand(
+	ctx context.Context,
+	com mod.Command,
+	res *mod.ResponseModel,
+	progress Progress,
+) (err error) {
 	// Extract the solve command
-	solve := mod.SolveCommand{
-		Array: com.Args.(map[string]interface{})["array"].([]interface{}),
-		Problem: com.Args.(map[string]interface{})["problem"].(string),
+	solve, e := DecodeSolveCommand(com)
+	if e != nil {
+		*res = *BadRequest(e)
+		return
+	}
+
+	// Solve every item of a batch
+	if len(solve.Batch) != 0 {
+		*res = *s.SolveBatch(ctx, solve, progress)
+		return
 	}
 
+	// Send the result
+	*res = *s.SolveItem(ctx, solve.Problem, solve.Array, progress)
+
+	// Return the result
+	return
+}
+
+// Check the input of a problem, returns the rejection response when it
+// cannot be solved
+func (s *Server) Validate(problem string, arr []interface{}) *mod.ResponseModel {
 	// Validate the data
-	if len(solve.Array) > s.Settings.MaxArrLen {
-		*res = mod.ResponseModel{
+	if len(arr) > s.Settings.MaxArrLen {
+		return &mod.ResponseModel{
 			Content: fmt.Sprintf("array length should be leq %v", s.Settings.MaxArrLen),
 			Status: mod.BADREQUEST,
 		}
-		return
 	}
 
-	// Solve the problem
+	// Validate the input against the schema of the problem
+	if p, ok := prob.Lookup(problem); !ok {
+		return &mod.ResponseModel{
+			Content: fmt.Sprintf("unknown problem %v", problem),
+			Status: mod.BADREQUEST,
+		}
+	} else if e := p.Validate(arr); e != nil {
+		return &mod.ResponseModel{
+			Content: e.Error(),
+			Status: mod.BADREQUEST,
+		}
+	}
+	return nil
+}
+
+// Validate the input and solve the problem on the worker pool
+func (s *Server) SolveItem(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+	progress Progress,
+) *mod.ResponseModel {
+	return s.solveItem(ctx, problem, arr, progress, false)
+}
+
+// Solve the problem, answering busy when the queue is full or, with
+// wait, waiting for room in the queue
+func (s *Server) solveItem(
+	ctx context.Context,
+	problem string,
+	arr []interface{},
+	progress Progress,
+	wait bool,
+) *mod.ResponseModel {
+	if reject := s.Validate(problem, arr); reject != nil {
+		return reject
+	}
+
+	// Wait for a worker to solve the problem
+	var sol interface{}
 	var e error
-	var sol string
-	if sol, e = s.Solve(solve.Problem, solve.Array); e != nil {
-		*res = mod.ResponseModel{
+	job := func() {
+		// Skip the requests cancelled while queued
+		if e = ctx.Err(); e == nil {
+			sol, e = s.Solve(ctx, problem, arr)
+		}
+	}
+	var ticket *pool.Ticket
+	var err error
+	if wait {
+		ticket, err = s.Pool.SubmitWait(ctx, job)
+	} else {
+		ticket, err = s.Pool.Submit(job)
+	}
+	if err == pool.ErrQueueFull {
+		return &mod.ResponseModel{
+			Content: "server busy, try again later",
+			Status: mod.BUSY,
+		}
+	} else if IsInterrupted(err) {
+		return Interrupted(err)
+	}
+
+	// Leave the queue as soon as the request is cancelled
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Pool.Withdraw(ticket, ctx.Err())
+		case <-ticket.Done():
+		}
+	}()
+
+	if err = ticket.Wait(func(position int) {
+		if position > 0 {
+			progress(fmt.Sprintf("request queued at position %v", position))
+		} else {
+			progress("request is being solved")
+		}
+	}); IsInterrupted(err) {
+		return Interrupted(err)
+	} else if err != nil {
+		s.Logger.Log(fmt.Sprintf("solving %v %v failed: %v\n", problem, arr, err))
+		return &mod.ResponseModel{
+			Content: "internal server error",
+			Status: mod.ERROR,
+		}
+	}
+
+	if IsInterrupted(e) {
+		return Interrupted(e)
+	} else if e != nil {
+		return &mod.ResponseModel{
 			Content: e.Error(),
 			Status: mod.ERROR,
 		}
-		return
 	}
 
-	// Send the result
-	*res = mod.ResponseModel{
-		Content: sol,
+	return &mod.ResponseModel{
+		Content: mod.SolveResult{
+			Problem: problem,
+			Result: sol,
+		},
 		Status: mod.OK,
 	}
+}
 
-	// Return the result
-	return
+// Number of requests processed at once for a connection
+func (s *Server) MaxInFlight() int {
+	if s.Settings.MaxInFlight <= 0 {
+		return 1
+	}
+	return s.Settings.MaxInFlight
+}
+
+// Commands a connection may have accepted at once, running or waiting
+// for one of the maxInFlight slots
+func (s *Server) MaxPending() int {
+	if s.Settings.MaxPending < s.MaxInFlight() {
+		return 4 * s.MaxInFlight()
+	}
+	return s.Settings.MaxPending
+}
+
+// Rejection of a command sent while the connection has too many pending
+func (s *Server) TooManyPending() *mod.ResponseModel {
+	return &mod.ResponseModel{
+		Content: fmt.Sprintf("more than %v commands pending on the connection", s.MaxPending()),
+		Status: mod.BUSY,
+	}
 }
 
 func (s *Server) Name() string {
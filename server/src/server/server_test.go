@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+	mod "aio/common/src/model"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name   string
+		owner  string // Client registered on the connection, empty if none
+		sender string
+		want   string // Status of the rejection, empty when authorized
+	}{
+		{"owner", "alice", "alice", ""},
+		{"other client", "alice", "bob", mod.BADSENDER},
+		{"no sender", "alice", "", mod.BADSENDER},
+		{"case differs", "alice", "Alice", mod.BADSENDER},
+		{"not registered", "", "alice", mod.UNREGISTERED},
+		{"not registered without sender", "", "", mod.UNREGISTERED},
+	}
+
+	s := new(Server)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := NewSession(nil, 0)
+			if tt.owner != "" {
+				session.Bind(tt.owner)
+			}
+
+			reject := s.Authorize(session, &mod.RequestModel{
+				Type: mod.COMMAND,
+				Sender: tt.sender,
+			})
+
+			got := ""
+			if reject != nil {
+				got = reject.Status
+			}
+			if got != tt.want {
+				t.Errorf("Authorize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
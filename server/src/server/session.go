@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"aio/common/src/codec"
+)
+
+// A Session binds a registered client name to the connection that
+// performed the salute handshake for it.
+type Session struct {
+	Conn	net.Conn
+	Codec	*codec.Codec
+	name	string
+	framing	string
+	running	map[string][]*running
+	busy	int
+	Mutex	sync.Mutex
+}
+
+func NewSession(conn net.Conn, maxFrameSize int) *Session {
+	return &Session{
+		Conn: conn,
+		Codec: codec.New(conn, maxFrameSize),
+	}
+}
+
+// Remember the framing negotiated at salute, applied once the salute
+// response was sent with the previous one
+func (ss *Session) NegotiateFraming(framing string) {
+	ss.Mutex.Lock()
+	ss.framing = framing
+	ss.Mutex.Unlock()
+}
+
+func (ss *Session) ApplyFraming() error {
+	ss.Mutex.Lock()
+	framing := ss.framing
+	ss.framing = ""
+	ss.Mutex.Unlock()
+
+	if framing == "" {
+		return nil
+	}
+	return ss.Codec.SetFraming(framing)
+}
+
+// Name of the client owning the connection, empty if not registered
+func (ss *Session) Owner() (name string) {
+	ss.Mutex.Lock()
+	name = ss.name
+	ss.Mutex.Unlock()
+	return
+}
+
+func (ss *Session) Bind(name string) {
+	ss.Mutex.Lock()
+	ss.name = name
+	ss.Mutex.Unlock()
+}
+
+func (ss *Session) Unbind() (name string) {
+	ss.Mutex.Lock()
+	name, ss.name = ss.name, ""
+	ss.Mutex.Unlock()
+	return
+}
+
+// Count a request in progress, the connection is not idle until it ends
+func (ss *Session) Begin() {
+	ss.Mutex.Lock()
+	ss.busy++
+	ss.Mutex.Unlock()
+}
+
+// Count the end of a request, reporting if none is left in progress
+func (ss *Session) End() (idle bool) {
+	ss.Mutex.Lock()
+	ss.busy--
+	idle = ss.busy == 0
+	ss.Mutex.Unlock()
+	return
+}
+
+func (ss *Session) IsRegistered() bool {
+	return ss.Owner() != ""
+}
+
+// Check if the sender of a request is the client owning the connection
+func (ss *Session) Owns(sender string) bool {
+	owner := ss.Owner()
+	return owner != "" && owner == sender
+}
+
+func (ss *Session) RemoteAddr() string {
+	if ss.Conn == nil {
+		return ""
+	}
+	return ss.Conn.RemoteAddr().String()
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	mod "aio/common/src/model"
+)
+
+// Returned by Start once Shutdown stopped the server
+var ErrServerClosed = errors.New("server closed")
+
+// Book-keeping of the open connections and the requests being processed
+type tracker struct {
+	sessions map[*Session]struct{}
+	inflight sync.WaitGroup
+	closing  bool
+	Mutex    sync.Mutex
+}
+
+// Start tracking the session of a new connection
+func (s *Server) Open(conn net.Conn) *Session {
+	session := NewSession(conn, s.Settings.MaxFrameSize)
+
+	s.tracker.Mutex.Lock()
+	if s.tracker.sessions == nil {
+		s.tracker.sessions = make(map[*Session]struct{})
+	}
+	s.tracker.sessions[session] = struct{}{}
+	s.tracker.Mutex.Unlock()
+
+	return session
+}
+
+func (s *Server) forget(session *Session) {
+	s.tracker.Mutex.Lock()
+	delete(s.tracker.sessions, session)
+	s.tracker.Mutex.Unlock()
+}
+
+// Mark the start of a request, returning the function marking its end.
+// No new requests are accepted once the server is shutting down.
+func (s *Server) Track() (done func(), ok bool) {
+	s.tracker.Mutex.Lock()
+	defer s.tracker.Mutex.Unlock()
+
+	if s.tracker.closing {
+		return nil, false
+	}
+
+	s.tracker.inflight.Add(1)
+	return s.tracker.inflight.Done, true
+}
+
+func (s *Server) IsClosing() bool {
+	s.tracker.Mutex.Lock()
+	defer s.tracker.Mutex.Unlock()
+	return s.tracker.closing
+}
+
+func (s *Server) GoingAway() *mod.ResponseModel {
+	return &mod.ResponseModel{
+		Content: "server is going away",
+		Status:  mod.SHUTDOWN,
+	}
+}
+
+// Stop accepting connections, notify the clients, wait for the requests
+// being processed until the context is done and close the connections
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	s.tracker.Mutex.Lock()
+	if s.tracker.closing {
+		s.tracker.Mutex.Unlock()
+		return ErrServerClosed
+	}
+	s.tracker.closing = true
+	s.tracker.Mutex.Unlock()
+
+	// Stop accepting new connections
+	s.Logger.Log("shutting down...\n")
+	if s.Listener != nil && *s.Listener != nil {
+		(*s.Listener).Close()
+	}
+
+	// Tell the clients the server is leaving
+	for _, session := range s.openSessions() {
+		if e := s.Send(session, s.GoingAway()); e != nil {
+			s.Logger.Log("failed to notify ", session.RemoteAddr(), ": ", e, "\n")
+		}
+	}
+
+	// Wait for the requests being processed
+	drained := make(chan struct{})
+	go func() {
+		s.tracker.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.Logger.Log("all requests were answered\n")
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.Logger.Log("stopped waiting for requests: ", err, "\n")
+	}
+
+	// Close the remaining connections
+	for _, session := range s.openSessions() {
+		s.Release(session, ErrServerClosed)
+	}
+
+	return
+}
+
+func (s *Server) openSessions() []*Session {
+	s.tracker.Mutex.Lock()
+	sessions := make([]*Session, 0, len(s.tracker.sessions))
+	for session := range s.tracker.sessions {
+		sessions = append(sessions, session)
+	}
+	s.tracker.Mutex.Unlock()
+	return sessions
+}
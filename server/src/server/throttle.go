@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"aio/server/src/limits"
+	"aio/server/src/settings"
+	mod "aio/common/src/model"
+)
+
+// Rate limits and quotas on the problems solved by the clients
+type throttle struct {
+	names     *limits.Limiter
+	addresses *limits.Limiter
+	quota     *limits.Quota
+	logins    *limits.Limiter
+	Mutex     sync.Mutex
+}
+
+func newThrottle(l settings.LimitSettings) *throttle {
+	return &throttle{
+		names:     limits.New(l.Rate, l.Burst),
+		addresses: limits.New(l.IPRate, l.IPBurst),
+		quota:     limits.NewQuota(l.DailyQuota, l.TotalQuota),
+		logins:    limits.New(l.LoginRate, l.LoginBurst),
+	}
+}
+
+// Count the solve commands against the limits of the client and of its
+// address, returns the rejection response once they are exceeded. Only
+// the problems which pass validation are counted, and the returned
+// function gives the tokens and the quota back when the command is not
+// served after all.
+func (s *Server) Throttle(
+	session *Session,
+	client string,
+	com mod.Command,
+) (reject *mod.ResponseModel, refund func()) {
+	refund = func() {}
+	if com.Verb != mod.SOLVE && com.Verb != mod.SUBMIT {
+		return
+	}
+
+	solve, e := DecodeSolveCommand(com)
+	if e != nil {
+		return
+	}
+
+	// Commands without a valid problem are rejected without any work
+	n := s.validProblems(solve)
+	if n == 0 {
+		return
+	}
+
+	host := session.RemoteHost()
+	t := s.throttle
+
+	// Check every limit before taking from any of them
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	if ok, wait := t.names.Allow(client); !ok {
+		return RateLimited(wait, "too many requests from %s", client), refund
+	}
+	if ok, wait := t.addresses.Allow(host); !ok {
+		return RateLimited(wait, "too many requests from your address"), refund
+	}
+
+	limit := s.Settings.Limits
+	switch wait, err := t.quota.Use(client, n); err {
+	case limits.ErrTotalQuota:
+		return RateLimited(0, "total quota of %v problems exceeded", limit.TotalQuota), refund
+	case limits.ErrDailyQuota:
+		return RateLimited(wait, "daily quota of %v problems exceeded", limit.DailyQuota), refund
+	}
+
+	t.names.Take(client)
+	t.addresses.Take(host)
+	return nil, func() {
+		t.names.Put(client)
+		t.addresses.Put(host)
+		t.quota.Refund(client, n)
+	}
+}
+
+// Number of problems of the command which pass validation
+func (s *Server) validProblems(solve mod.SolveCommand) int {
+	if len(solve.Batch) == 0 {
+		if s.Validate(solve.Problem, solve.Array) != nil {
+			return 0
+		}
+		return 1
+	}
+
+	if s.Settings.MaxBatchLen > 0 && len(solve.Batch) > s.Settings.MaxBatchLen {
+		return 0
+	}
+
+	n := 0
+	problems := problemsOf(solve)
+	for i, item := range solve.Batch {
+		if s.Validate(problems[i], item.Array) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// Rejection hinting the client to try again after wait, unless it is 0
+func RateLimited(wait time.Duration, format string, v ...interface{}) *mod.ResponseModel {
+	content := fmt.Sprintf(format, v...)
+	if wait > 0 {
+		content += fmt.Sprintf(", retry in %v", wait.Round(time.Millisecond))
+	}
+
+	return &mod.ResponseModel{
+		Content: content,
+		Status: mod.RATELIMITED,
+		RetryAfter: (wait + time.Millisecond - 1) / time.Millisecond,
+	}
+}
+
+// Address of the client without its port, shared by its connections
+func (ss *Session) RemoteHost() string {
+	addr := ss.RemoteAddr()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,13 @@
+package settings
+
+// Limits on the problems the clients may solve, 0 disabling a limit
+type LimitSettings struct {
+	Rate       float64 `json:"rate"`       // Solve commands per second of a client
+	Burst      int     `json:"burst"`      // Solve commands a client may send at once
+	IPRate     float64 `json:"ipRate"`     // Solve commands per second from an address
+	IPBurst    int     `json:"ipBurst"`    // Solve commands an address may send at once
+	DailyQuota int     `json:"dailyQuota"` // Problems a client may solve per day
+	TotalQuota int     `json:"totalQuota"` // Problems a client may solve in total
+	LoginRate  float64 `json:"loginRate"`  // Failed salutes per second from an address
+	LoginBurst int     `json:"loginBurst"` // Failed salutes an address may send at once
+}
@@ -0,0 +1,63 @@
+package settings
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules the names of the clients must follow to register
+type NamePolicy struct {
+	MinLen   int      `json:"minLen"`
+	MaxLen   int      `json:"maxLen"`
+	Pattern  string   `json:"pattern"`
+	Reserved []string `json:"reserved"`
+	pattern  *regexp.Regexp
+}
+
+func (np *NamePolicy) compile() (err error) {
+	if np.Pattern == "" {
+		return nil
+	}
+	if np.pattern, err = regexp.Compile(np.Pattern); err != nil {
+		return fmt.Errorf("invalid name pattern: %v", err)
+	}
+	return nil
+}
+
+// Check the name, returning the reason it is refused. The reserved
+// names and the extra ones given are compared ignoring the case.
+func (np *NamePolicy) Check(name string, reserved ...string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("name is not valid utf-8")
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("name cannot contain control characters")
+		}
+	}
+
+	length := utf8.RuneCountInString(name)
+	if length < np.MinLen {
+		return fmt.Errorf("name should have at least %v characters", np.MinLen)
+	}
+	if np.MaxLen > 0 && length > np.MaxLen {
+		return fmt.Errorf("name should have at most %v characters", np.MaxLen)
+	}
+
+	if np.pattern != nil && !np.pattern.MatchString(name) {
+		return fmt.Errorf("name should match %s", np.Pattern)
+	}
+
+	for _, r := range append(reserved, np.Reserved...) {
+		if strings.EqualFold(name, r) {
+			return fmt.Errorf("name %s is reserved", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamePolicyCheck(t *testing.T) {
+	policy := NamePolicy{
+		MinLen:   2,
+		MaxLen:   8,
+		Pattern:  "^[A-Za-z0-9_.-]+$",
+		Reserved: []string{"admin", "system"},
+	}
+	if err := policy.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		client   string
+		reserved []string // Extra reserved names given to Check
+		wantErr  string   // Part of the reason, empty when accepted
+	}{
+		{"valid", "alice", nil, ""},
+		{"pattern characters", "a.b_c-1", nil, ""},
+		{"at min length", "ab", nil, ""},
+		{"at max length", "abcdefgh", nil, ""},
+		{"empty", "", nil, "empty"},
+		{"blank", "   ", nil, "empty"},
+		{"too short", "a", nil, "at least 2"},
+		{"too long", "abcdefghi", nil, "at most 8"},
+		{"invalid utf-8", "ab\xff", nil, "utf-8"},
+		{"newline", "ab\ncd", nil, "control"},
+		{"escape", "ab\x1bcd", nil, "control"},
+		{"nul", "ab\x00", nil, "control"},
+		{"space", "al ice", nil, "match"},
+		{"outside the pattern", "alicé", nil, "match"},
+		{"reserved", "admin", nil, "reserved"},
+		{"reserved in another case", "AdMiN", nil, "reserved"},
+		{"extra reserved", "Server", []string{"Server"}, "reserved"},
+		{"extra reserved in another case", "server", []string{"Server"}, "reserved"},
+		{"extra reserved is not listed", "bob", []string{"Server"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.client, tt.reserved...)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Check(%q) = %v, want nil", tt.client, err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("Check(%q) = nil, want an error with %q", tt.client, tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("Check(%q) = %v, want an error with %q", tt.client, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Without limits only the empty names and the control characters are
+// refused, the length counts characters rather than bytes
+func TestNamePolicyDefaults(t *testing.T) {
+	tests := []struct {
+		policy  NamePolicy
+		client  string
+		wantErr bool
+	}{
+		{NamePolicy{}, "any name at all, even long", false},
+		{NamePolicy{}, "ünïcödé", false},
+		{NamePolicy{}, "tab\there", true},
+		{NamePolicy{MaxLen: 3}, "äöü", false},
+		{NamePolicy{MaxLen: 3}, "äöüß", true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.policy.Check(tt.client); (err != nil) != tt.wantErr {
+			t.Errorf("Check(%q) with %+v = %v, wantErr %v", tt.client, tt.policy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNamePolicyCompile(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"", false},
+		{"^[a-z]+$", false},
+		{"^[a-z+$", true},
+	}
+
+	for _, tt := range tests {
+		np := NamePolicy{Pattern: tt.pattern}
+		if err := np.compile(); (err != nil) != tt.wantErr {
+			t.Errorf("compile(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
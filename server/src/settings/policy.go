@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Matches every verb, list entity or problem
+const Any = "*"
+
+// What the clients holding a role may do
+type Role struct {
+	Verbs    []string `json:"verbs"`
+	Lists    []string `json:"lists"`
+	Problems []string `json:"problems"`
+}
+
+// Policy maps the registered clients to their roles. The clients which
+// are not listed get the default role, or are denied everything when
+// there is none.
+type Policy struct {
+	Default string            `json:"default"`
+	Roles   map[string]*Role  `json:"roles"`
+	Clients map[string]string `json:"clients"`
+}
+
+func ReadPolicy(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the policy file %s: %v", path, err)
+	}
+
+	policy := new(Policy)
+	if err = json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("cannot parse the policy file: %v", err)
+	}
+
+	// Catch the misspelled roles at startup
+	if policy.Default != "" && policy.Roles[policy.Default] == nil {
+		return nil, fmt.Errorf("unknown default role %s", policy.Default)
+	}
+	for client, role := range policy.Clients {
+		if policy.Roles[role] == nil {
+			return nil, fmt.Errorf("unknown role %s of client %s", role, client)
+		}
+	}
+	return policy, nil
+}
+
+// Role of the client, nil when it has none
+func (p *Policy) RoleOf(client string) *Role {
+	if role, ok := p.Clients[client]; ok {
+		return p.Roles[role]
+	}
+	return p.Roles[p.Default]
+}
+
+func (r *Role) CanUse(verb string) bool {
+	return r != nil && matches(r.Verbs, verb)
+}
+
+func (r *Role) CanList(entity string) bool {
+	return r != nil && matches(r.Lists, entity)
+}
+
+func (r *Role) CanSolve(problem string) bool {
+	return r != nil && matches(r.Problems, problem)
+}
+
+func matches(allowed []string, value string) bool {
+	for _, a := range allowed {
+		if a == Any || a == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package settings
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var testPolicy = &Policy{
+	Default: "guest",
+	Roles: map[string]*Role{
+		"admin": {Verbs: []string{Any}, Lists: []string{Any}, Problems: []string{Any}},
+		"solver": {
+			Verbs:    []string{"solve", "list"},
+			Lists:    []string{"problems"},
+			Problems: []string{"1", "3"},
+		},
+		"guest": {Verbs: []string{"list"}, Lists: []string{"problems"}},
+	},
+	Clients: map[string]string{
+		"root":  "admin",
+		"alice": "solver",
+	},
+}
+
+func TestRoleOf(t *testing.T) {
+	noDefault := &Policy{Roles: testPolicy.Roles, Clients: testPolicy.Clients}
+
+	tests := []struct {
+		name   string
+		policy *Policy
+		client string
+		want   *Role
+	}{
+		{"listed", testPolicy, "alice", testPolicy.Roles["solver"]},
+		{"admin", testPolicy, "root", testPolicy.Roles["admin"]},
+		{"default", testPolicy, "bob", testPolicy.Roles["guest"]},
+		{"case differs", testPolicy, "Alice", testPolicy.Roles["guest"]},
+		{"no default", noDefault, "bob", nil},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.RoleOf(tt.client); got != tt.want {
+			t.Errorf("%s: RoleOf(%q) = %+v, want %+v", tt.name, tt.client, got, tt.want)
+		}
+	}
+}
+
+func TestRole(t *testing.T) {
+	type check struct {
+		kind  string // verb, list or problem
+		value string
+		want  bool
+	}
+
+	tests := []struct {
+		client string
+		checks []check
+	}{
+		{"root", []check{
+			{"verb", "solve", true},
+			{"verb", "submit", true},
+			{"list", "clients", true},
+			{"problem", "8", true},
+		}},
+		{"alice", []check{
+			{"verb", "solve", true},
+			{"verb", "submit", false},
+			{"list", "problems", true},
+			{"list", "clients", false},
+			{"problem", "1", true},
+			{"problem", "8", false},
+			{"problem", "", false},
+		}},
+		{"bob", []check{
+			{"verb", "list", true},
+			{"verb", "solve", false},
+			{"list", "problems", true},
+			{"list", "jobs", false},
+			{"problem", "1", false},
+		}},
+	}
+
+	for _, tt := range tests {
+		role := testPolicy.RoleOf(tt.client)
+		for _, c := range tt.checks {
+			var got bool
+			switch c.kind {
+			case "verb":
+				got = role.CanUse(c.value)
+			case "list":
+				got = role.CanList(c.value)
+			case "problem":
+				got = role.CanSolve(c.value)
+			}
+			if got != c.want {
+				t.Errorf("%s may %s %q = %v, want %v", tt.client, c.kind, c.value, got, c.want)
+			}
+		}
+	}
+}
+
+// A client without a role may do nothing
+func TestNilRole(t *testing.T) {
+	var role *Role
+	if role.CanUse("list") || role.CanList("problems") || role.CanSolve("1") {
+		t.Fatal("a nil role allows something")
+	}
+}
+
+func TestReadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{"valid", `{"default":"r","roles":{"r":{"verbs":["*"]}},"clients":{"a":"r"}}`, false},
+		{"without default", `{"roles":{"r":{}},"clients":{"a":"r"}}`, false},
+		{"unknown default", `{"default":"x","roles":{"r":{}}}`, true},
+		{"unknown client role", `{"roles":{"r":{}},"clients":{"a":"x"}}`, true},
+		{"not json", `roles: r`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := ioutil.WriteFile(path, []byte(tt.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ReadPolicy(path); (err != nil) != tt.wantErr {
+				t.Errorf("ReadPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -11,8 +11,24 @@ import (
 type ServerSettings struct {
 	ErrorPolling	time.Duration 	   `json:"errorPolling"`
 	MaxClients		int				   `json:"maxClients"`
+	RejectOnAccept	bool			   `json:"rejectOnAccept"`
+	IdleTimeout		time.Duration	   `json:"idleTimeout"`
+	ShutdownTimeout	time.Duration	   `json:"shutdownTimeout"`
 	Name			string 			   `json:"serverName"`
 	MaxArrLen		int				   `json:"maxArrLen"`
+	MaxBatchLen		int				   `json:"maxBatchLen"`
+	MaxFrameSize	int				   `json:"maxFrameSize"`
+	MaxInFlight		int				   `json:"maxInFlight"`
+	MaxPending		int				   `json:"maxPending"`
+	Workers			int				   `json:"workers"`
+	QueueDepth		int				   `json:"queueDepth"`
+	JobRetention	time.Duration	   `json:"jobRetention"`
+	MaxJobs			int				   `json:"maxJobs"`
+	Users			string			   `json:"users"`
+	PolicyFile		string			   `json:"policy"`
+	Policy			*Policy			   `json:"-"`
+	Names			NamePolicy		   `json:"names"`
+	Limits			LimitSettings	   `json:"limits"`
 	Host			*host.HostSettings `json:"host"`
 }
 
@@ -32,5 +48,16 @@ func ReadSettings(configFilePath string) (*ServerSettings, error) {
 		return nil, fmt.Errorf("cannot parse the server settings file: %v", err)
 	}
 
+	if err = serverSettings.Names.compile(); err != nil {
+		return nil, err
+	}
+
+	// Every client may do anything without a policy
+	if serverSettings.PolicyFile != "" {
+		if serverSettings.Policy, err = ReadPolicy(serverSettings.PolicyFile); err != nil {
+			return nil, err
+		}
+	}
+
 	return serverSettings, nil
 }
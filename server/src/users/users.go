@@ -0,0 +1,162 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Iterations used for the new hashes, the stored ones keep their own
+const Iterations = 100000
+
+// Prefix of the hashes, followed by the iterations, salt and digest
+const scheme = "pbkdf2-sha256"
+
+// A client allowed to register, with the hashes of its password and of
+// the bearer tokens it may use instead
+type User struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password,omitempty"`
+	Tokens   []string `json:"tokens,omitempty"`
+}
+
+// Store of the users read from the user file
+type Store struct {
+	users map[string]*User
+}
+
+// Read the user file, a json object listing the users
+func Load(path string) (*Store, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the user file %s: %v", path, err)
+	}
+
+	var file struct {
+		Users []User `json:"users"`
+	}
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse the user file: %v", err)
+	}
+
+	st := &Store{users: make(map[string]*User)}
+	for i := range file.Users {
+		u := &file.Users[i]
+		if _, exists := st.users[u.Name]; exists {
+			return nil, fmt.Errorf("user %s is listed twice", u.Name)
+		}
+
+		// Catch the malformed hashes at startup rather than at login
+		hashes := append([]string{u.Password}, u.Tokens...)
+		for _, h := range hashes {
+			if h == "" {
+				continue
+			}
+			if _, _, _, err = parse(h); err != nil {
+				return nil, fmt.Errorf("user %s: %v", u.Name, err)
+			}
+		}
+		st.users[u.Name] = u
+	}
+	return st, nil
+}
+
+// Check the password or the token of the user, either one is enough
+func (st *Store) Authenticate(name string, password string, token string) bool {
+	u, ok := st.users[name]
+	if !ok {
+		// Spend the same time as for a known user
+		Verify(dummy, password+token)
+		return false
+	}
+
+	if password != "" && u.Password != "" && Verify(u.Password, password) {
+		return true
+	}
+	if token != "" {
+		for _, h := range u.Tokens {
+			if Verify(h, token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var dummy, _ = Hash("")
+
+// Hash the secret with a random salt, in the format of the user file
+func Hash(secret string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("cannot generate a salt: %v", err)
+	}
+
+	digest := derive(secret, salt, Iterations)
+	return fmt.Sprintf(
+		"%s$%d$%s$%s",
+		scheme,
+		Iterations,
+		hex.EncodeToString(salt),
+		hex.EncodeToString(digest),
+	), nil
+}
+
+// Check the secret against a hash produced by Hash
+func Verify(hash string, secret string) bool {
+	iterations, salt, digest, err := parse(hash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(derive(secret, salt, iterations), digest) == 1
+}
+
+func parse(hash string) (iterations int, salt []byte, digest []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != scheme {
+		return 0, nil, nil, fmt.Errorf("hash should look like %s$iterations$salt$digest", scheme)
+	}
+
+	if iterations, err = strconv.Atoi(parts[1]); err != nil || iterations <= 0 {
+		return 0, nil, nil, fmt.Errorf("invalid hash iterations %q", parts[1])
+	}
+	if salt, err = hex.DecodeString(parts[2]); err != nil {
+		return 0, nil, nil, fmt.Errorf("invalid hash salt: %v", err)
+	}
+	if digest, err = hex.DecodeString(parts[3]); err != nil || len(digest) != sha256.Size {
+		return 0, nil, nil, fmt.Errorf("invalid hash digest")
+	}
+	return
+}
+
+// PBKDF2 with HMAC-SHA256 (RFC 8018), deriving a key of one block
+func derive(secret string, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, []byte(secret))
+
+	// U1 = PRF(secret, salt || INT(1))
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], 1)
+	prf.Write(salt)
+	prf.Write(index[:])
+	u := prf.Sum(nil)
+
+	// Ui = PRF(secret, Ui-1), the key is U1 ^ U2 ^ ... ^ Uc
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
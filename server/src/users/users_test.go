@@ -0,0 +1,140 @@
+package users
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// Vectors of PBKDF2-HMAC-SHA256 from RFC 7914 and the usual RFC 6070
+// inputs, truncated to one block
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		secret     string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"},
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+
+	for _, tt := range tests {
+		got := hex.EncodeToString(derive(tt.secret, []byte(tt.salt), tt.iterations))
+		if got != tt.want {
+			t.Errorf("derive(%q, %q, %v) = %v, want %v", tt.secret, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	hash, err := Hash("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hash   string
+		secret string
+		want   bool
+	}{
+		{"match", hash, "s3cret", true},
+		{"other secret", hash, "s3cret ", false},
+		{"empty secret", hash, "", false},
+		{"known hash", "pbkdf2-sha256$1$73616c74$120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b", "password", true},
+		{"other scheme", "sha256$1$73616c74$120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b", "password", false},
+		{"no iterations", "pbkdf2-sha256$0$73616c74$120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b", "password", false},
+		{"short digest", "pbkdf2-sha256$1$73616c74$120fb6", "password", false},
+		{"bad salt", "pbkdf2-sha256$1$salt$120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b", "password", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := Verify(tt.hash, tt.secret); got != tt.want {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHashSalt(t *testing.T) {
+	a, _ := Hash("same")
+	b, _ := Hash("same")
+	if a == b {
+		t.Fatal("two hashes of the same secret are equal")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	password, _ := Hash("pw")
+	token, _ := Hash("tk")
+	st := &Store{users: map[string]*User{
+		"alice": {Name: "alice", Password: password},
+		"ci":    {Name: "ci", Tokens: []string{password, token}},
+	}}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		token    string
+		want     bool
+	}{
+		{"password", "alice", "pw", "", true},
+		{"wrong password", "alice", "tk", "", false},
+		{"token of a user without tokens", "alice", "", "pw", false},
+		{"password and wrong token", "alice", "pw", "nope", true},
+		{"token", "ci", "", "tk", true},
+		{"second token", "ci", "", "pw", true},
+		{"password of a user without one", "ci", "tk", "", false},
+		{"no credentials", "alice", "", "", false},
+		{"unknown user", "bob", "pw", "", false},
+		{"name differs in case", "Alice", "pw", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := st.Authenticate(tt.user, tt.password, tt.token); got != tt.want {
+				t.Errorf("Authenticate(%q, %q, %q) = %v, want %v",
+					tt.user, tt.password, tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	valid := "pbkdf2-sha256$1$73616c74$120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"
+
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{"users", `{"users":[{"name":"a","password":"` + valid + `"},{"name":"b","tokens":["` + valid + `"]}]}`, false},
+		{"no users", `{"users":[]}`, false},
+		{"listed twice", `{"users":[{"name":"a","password":"` + valid + `"},{"name":"a"}]}`, true},
+		{"malformed password", `{"users":[{"name":"a","password":"plain"}]}`, true},
+		{"malformed token", `{"users":[{"name":"a","tokens":["plain"]}]}`, true},
+		{"not json", `users: a`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users.json")
+			if err := ioutil.WriteFile(path, []byte(tt.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Load(path); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+}